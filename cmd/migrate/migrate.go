@@ -40,8 +40,28 @@ func applyMigrations(db *sql.DB) error {
 		return fmt.Errorf("❌ Failed to read migrations directory: %v", err)
 	}
 
+	// ✅ Track applied migrations so table rebuilds only run once
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("❌ Failed to create schema_migrations table: %v", err)
+	}
+
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".up.sql") {
+			var applied bool
+			err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)", file.Name()).Scan(&applied)
+			if err != nil {
+				return fmt.Errorf("❌ Failed to check migration %s: %v", file.Name(), err)
+			}
+			if applied {
+				fmt.Println("⏭️ Skipping applied migration:", file.Name())
+				continue
+			}
+
 			migrationPath := filepath.Join(absPath, file.Name())
 			migrationSQL, err := os.ReadFile(migrationPath)
 			if err != nil {
@@ -55,6 +75,9 @@ func applyMigrations(db *sql.DB) error {
 			if err != nil {
 				return fmt.Errorf("❌ Failed to execute migration %s: %v", file.Name(), err)
 			}
+			if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", file.Name()); err != nil {
+				return fmt.Errorf("❌ Failed to record migration %s: %v", file.Name(), err)
+			}
 			fmt.Println("✅ Applied migration:", file.Name())
 		}
 	}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"social-network/internal/mailer"
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+)
+
+// emailDigestPeriod is how often opted-in users are emailed their unread notifications
+const emailDigestPeriod = 24 * time.Hour
+
+// emailDigestCheckInterval is how often users are checked for a due digest, so a restart
+// delays a digest by at most this long
+const emailDigestCheckInterval = time.Hour
+
+// maxDigestNotifications caps how many notifications a digest email lists
+const maxDigestNotifications = 20
+
+// digestEmail is the data behind the digest email templates
+type digestEmail struct {
+	Nickname         string
+	Notifications    []models.Notification
+	Total            int
+	More             int
+	NotificationsURL string
+	UnsubscribeURL   string
+}
+
+// runEmailDigests emails each opted-in user a summary of their unread notifications once per
+// emailDigestPeriod. It checks for due digests now and then on every tick; when each user's last
+// digest went out is stored, so restarts neither skip nor repeat digests.
+func runEmailDigests(db *sql.DB, mail mailer.Mailer, interval time.Duration) {
+	sendEmailDigests(db, mail)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sendEmailDigests(db, mail)
+	}
+}
+
+// sendEmailDigests emails every recipient whose digest is due and who has unread digest
+// notifications since their last digest. A user's window only moves forward once their email
+// was sent, so failures are retried on the next check.
+func sendEmailDigests(db *sql.DB, mail mailer.Mailer) {
+	settingsRepo := repositories.NewNotificationSettingsRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+
+	recipients, err := settingsRepo.GetEmailDigestRecipients(emailDigestPeriod)
+	if err != nil {
+		log.Println("❌ Failed to load email digest recipients:", err)
+		return
+	}
+
+	for _, recipient := range recipients {
+		since, until, err := settingsRepo.GetEmailDigestWindow(recipient.UserID)
+		if err != nil {
+			log.Printf("❌ Failed to load email digest window for User %d: %v", recipient.UserID, err)
+			continue
+		}
+		notifications, err := notificationRepo.GetEmailDigestNotifications(recipient.UserID, since, until)
+		if err != nil {
+			log.Printf("❌ Failed to build email digest for User %d: %v", recipient.UserID, err)
+			continue
+		}
+		if len(notifications) == 0 {
+			continue
+		}
+
+		token, err := settingsRepo.GetOrCreateUnsubscribeToken(recipient.UserID)
+		if err != nil {
+			log.Printf("❌ Failed to create unsubscribe token for User %d: %v", recipient.UserID, err)
+			continue
+		}
+		data := digestEmail{
+			Nickname:         recipient.Nickname,
+			Notifications:    notifications,
+			Total:            len(notifications),
+			NotificationsURL: mailer.BaseURL() + "/notifications",
+			UnsubscribeURL:   mailer.BaseURL() + "/email/unsubscribe/" + token,
+		}
+		if len(notifications) > maxDigestNotifications {
+			data.Notifications = notifications[:maxDigestNotifications]
+			data.More = len(notifications) - maxDigestNotifications
+		}
+
+		subject := fmt.Sprintf("You have %d unread notifications", len(notifications))
+		if len(notifications) == 1 {
+			subject = "You have 1 unread notification"
+		}
+		msg, err := mailer.Compose(recipient.Email, subject, "digest", data)
+		if err != nil {
+			log.Printf("❌ Failed to render email digest for User %d: %v", recipient.UserID, err)
+			continue
+		}
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+		if err := mail.Send(msg); err != nil {
+			log.Printf("❌ Failed to send email digest to User %d: %v", recipient.UserID, err)
+			continue
+		}
+
+		if err := settingsRepo.MarkEmailDigestSent(recipient.UserID, until); err != nil {
+			log.Printf("❌ Failed to record email digest for User %d: %v", recipient.UserID, err)
+			continue
+		}
+		log.Printf("📧 Sent email digest with %d notifications to User %d", len(notifications), recipient.UserID)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+	websockets "social-network/internal/websocket"
+)
+
+// eventReminderInterval is how often upcoming events are checked for due reminders
+const eventReminderInterval = time.Minute
+
+// runEventReminders sends event reminders now and then on every tick. Sent reminders are
+// recorded in the database, so restarting the server doesn't repeat them.
+func runEventReminders(db *sql.DB, interval time.Duration) {
+	sendEventReminders(db, time.Now().UTC())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		sendEventReminders(db, now.UTC())
+	}
+}
+
+// sendEventReminders notifies users whose reminder offsets have been reached for events they're
+// going to or might go to. If several offsets are due at once (e.g. after a late RSVP or downtime)
+// the user gets a single reminder.
+func sendEventReminders(db *sql.DB, now time.Time) {
+	reminderRepo := repositories.NewEventReminderRepository(db)
+	settingsRepo := repositories.NewNotificationSettingsRepository(db)
+
+	candidates, err := reminderRepo.GetReminderCandidates(now, now.Add(models.MaxReminderOffset*time.Minute))
+	if err != nil {
+		log.Println("❌ Failed to load event reminder candidates:", err)
+		return
+	}
+
+	offsetsByUser := make(map[int][]int)
+	for _, candidate := range candidates {
+		offsets, ok := offsetsByUser[candidate.UserID]
+		if !ok {
+			settings, err := settingsRepo.GetSettings(candidate.UserID)
+			if err != nil {
+				log.Printf("❌ Failed to load reminder settings for User %d: %v", candidate.UserID, err)
+				continue
+			}
+			offsets = settings.ReminderOffsets
+			offsetsByUser[candidate.UserID] = offsets
+		}
+
+		start, _ := candidate.Event.StartTime()
+		var due []int
+		for _, offset := range offsets {
+			if !start.After(now.Add(time.Duration(offset) * time.Minute)) {
+				due = append(due, offset)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		claimed, err := reminderRepo.ClaimReminders(candidate.Event.ID, candidate.Event.OccurrenceDate, candidate.UserID, due)
+		if err != nil {
+			log.Printf("❌ Failed to record reminder for User %d: %v", candidate.UserID, err)
+			continue
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		websockets.Notify(models.Notification{
+			UserID: candidate.UserID,
+			Type:   "event_reminder",
+			Message: fmt.Sprintf("Reminder: \"%s\" starts in %s (%s).",
+				candidate.Event.Title, describeTimeUntil(start.Sub(now)), candidate.Event.EventDate),
+			EntityType: models.NotificationEntityEvent,
+			EntityID:   candidate.Event.ID,
+			Data:       candidate.Event.NotificationData(),
+		})
+	}
+}
+
+// describeTimeUntil renders a duration roughly, e.g. "2 days", "5 hours" or "45 minutes"
+func describeTimeUntil(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int(d.Round(24*time.Hour)/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int(d.Round(time.Hour)/time.Hour), "hour")
+	default:
+		minutes := int(d.Round(time.Minute) / time.Minute)
+		if minutes < 1 {
+			minutes = 1
+		}
+		return plural(minutes, "minute")
+	}
+}
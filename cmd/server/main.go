@@ -3,12 +3,13 @@ package main
 import (
 	"log"
 	"net/http"
-	"strconv"
 
 	"social-network/internal/config"
 	"social-network/internal/handlers"
+	"social-network/internal/mailer"
 	"social-network/internal/middlewares"
 	"social-network/internal/repositories"
+	"social-network/internal/webhooks"
 	websockets "social-network/internal/websocket"
 
 	"github.com/gorilla/mux"
@@ -16,10 +17,8 @@ import (
 )
 
 var (
-	upgrader            = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
-	chatManager         = websockets.NewChatManager()
-	groupChatManager    = websockets.NewGroupChatManager()
-	notificationManager = websockets.NewWebSocketNotificationManager()
+	upgrader    = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	chatManager = websockets.NewChatManager()
 )
 
 func main() {
@@ -46,13 +45,20 @@ func main() {
 	r.HandleFunc("/login", handlers.LoginUser).Methods("POST")
 	r.HandleFunc("/logout", handlers.LogoutUser).Methods("POST")
 
+	// ✅ Calendar subscription feed (authenticated by the token in the URL)
+	r.HandleFunc("/calendar/{token:[0-9a-f]+}.ics", handlers.CalendarFeedHandler).Methods("GET")
+
+	// ✅ Email unsubscribe links (authenticated by the token in the URL)
+	r.HandleFunc("/email/unsubscribe/{token:[0-9a-f]+}", handlers.ConfirmUnsubscribeEmailHandler).Methods("GET")
+	r.HandleFunc("/email/unsubscribe/{token:[0-9a-f]+}", handlers.UnsubscribeEmailHandler).Methods("POST")
+
 	// ✅ Serve uploaded images
 	r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads"))))
 
 	// ✅ WebSocket Routes (Chat & Notifications)
 	r.HandleFunc("/ws/chat", WebSocketChatHandler)
-	r.HandleFunc("/ws/group-chat", WebSocketGroupChatHandler)
-	r.HandleFunc("/ws/notifications", WebSocketNotificationHandler)
+	r.HandleFunc("/ws/group-chat", handlers.WebSocketGroupChatHandler)
+	r.HandleFunc("/ws/notifications", handlers.WebSocketNotificationHandler)
 
 	// ✅ Protected Routes (Require Authentication)
 	authRoutes := r.PathPrefix("/api").Subrouter()
@@ -63,6 +69,7 @@ func main() {
 	authRoutes.HandleFunc("/comments", handlers.GetCommentsForPostHandler).Methods("GET")
 	authRoutes.HandleFunc("/comments/edit", handlers.EditCommentHandler).Methods("PUT")
 	authRoutes.HandleFunc("/comments", handlers.DeleteCommentHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/comments/{id:[0-9]+}/replies", handlers.GetCommentRepliesHandler).Methods("GET")
 
 	authRoutes.HandleFunc("/all-posts", handlers.GetAllPostsHandler).Methods("GET")
 	authRoutes.HandleFunc("/user-posts", handlers.GetUserPostsHandler).Methods("GET")
@@ -78,10 +85,23 @@ func main() {
 	authRoutes.HandleFunc("/groups", handlers.CreateGroupHandler).Methods("POST")
 	authRoutes.HandleFunc("/groups/members", handlers.GetGroupMembersHandler).Methods("GET")
 	authRoutes.HandleFunc("/groups/posts", handlers.CreateGroupPostHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/posts", handlers.GetGroupPostsHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/posts/{postID:[0-9]+}", handlers.GetGroupPostHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/posts/{postID:[0-9]+}", handlers.EditGroupPostHandler).Methods("PUT")
+	authRoutes.HandleFunc("/groups/posts/{postID:[0-9]+}", handlers.DeleteGroupPostHandler).Methods("DELETE")
 	authRoutes.HandleFunc("/groups/events", handlers.CreateGroupEventHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/events", handlers.GetGroupEventsHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/events/{eventID:[0-9]+}", handlers.GetGroupEventHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/events/{eventID:[0-9]+}", handlers.UpdateGroupEventHandler).Methods("PUT")
+	authRoutes.HandleFunc("/groups/events/{eventID:[0-9]+}/cancel", handlers.CancelGroupEventHandler).Methods("POST")
 	authRoutes.HandleFunc("/groups/events/rsvp", handlers.RSVPEventHandler).Methods("POST")
 	authRoutes.HandleFunc("/groups/events/rsvp/count", handlers.GetRSVPCountHandler).Methods("GET")
-	authRoutes.HandleFunc("/groups/events/create", handlers.CreateGroupEventHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/events/rsvp/breakdown", handlers.GetRSVPBreakdownHandler).Methods("GET")
+
+	// ✅ Calendar Export
+	authRoutes.HandleFunc("/events/{eventID:[0-9]+}.ics", handlers.GetEventICSHandler).Methods("GET")
+	authRoutes.HandleFunc("/calendar/token", handlers.GetCalendarTokenHandler).Methods("GET")
+	authRoutes.HandleFunc("/calendar/token", handlers.ResetCalendarTokenHandler).Methods("POST")
 
 	// ✅ Group Membership
 	authRoutes.HandleFunc("/groups/join", handlers.RequestToJoinGroupHandler).Methods("POST")
@@ -90,17 +110,69 @@ func main() {
 	authRoutes.HandleFunc("/groups/leave", handlers.LeaveGroupHandler).Methods("POST")
 	authRoutes.HandleFunc("/groups/chat/history", handlers.GetGroupChatHistoryHandler).Methods("GET")
 	authRoutes.HandleFunc("/groups/chat/send", handlers.SendGroupChatMessageHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/chat/messages", handlers.DeleteGroupChatMessageHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/members/{userID:[0-9]+}/role", handlers.SetGroupMemberRoleHandler).Methods("PUT")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/members/{userID:[0-9]+}", handlers.RemoveGroupMemberHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/bans", handlers.BanGroupMemberHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/bans", handlers.GetGroupBansHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/bans/{userID:[0-9]+}", handlers.UnbanGroupMemberHandler).Methods("DELETE")
+
+	// ✅ Group Ownership & Lifecycle
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}", handlers.GetGroupHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}", handlers.UpdateGroupHandler).Methods("PUT")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}", handlers.DeleteGroupHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/archive", handlers.ArchiveGroupHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/archive", handlers.UnarchiveGroupHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/transfer-ownership", handlers.TransferGroupOwnershipHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/transfer-ownership/accept", handlers.AcceptGroupOwnershipHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/transfer-ownership/decline", handlers.DeclineGroupOwnershipHandler).Methods("POST")
+
+	// ✅ Group Invite Links
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/invite-links", handlers.CreateGroupInviteLinkHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/invite-links", handlers.GetGroupInviteLinksHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/invite-links/{linkID:[0-9]+}", handlers.RevokeGroupInviteLinkHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/groups/join-by-link", handlers.JoinGroupByLinkHandler).Methods("POST")
+
+	// ✅ Group Webhooks
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks", handlers.CreateWebhookHandler).Methods("POST")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks", handlers.GetWebhooksHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks/{webhookID:[0-9]+}", handlers.UpdateWebhookHandler).Methods("PUT")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks/{webhookID:[0-9]+}", handlers.DeleteWebhookHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks/{webhookID:[0-9]+}/deliveries", handlers.GetWebhookDeliveriesHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks/{webhookID:[0-9]+}/deliveries/{deliveryID:[0-9]+}", handlers.GetWebhookDeliveryHandler).Methods("GET")
+	authRoutes.HandleFunc("/groups/{id:[0-9]+}/webhooks/{webhookID:[0-9]+}/deliveries/{deliveryID:[0-9]+}/redeliver", handlers.RedeliverWebhookHandler).Methods("POST")
 
 	// ✅ Notifications
 	authRoutes.HandleFunc("/notifications", handlers.GetNotificationsHandler).Methods("GET")
-	authRoutes.HandleFunc("/notifications", handlers.GetNotificationsHandler).Methods("GET")
+	authRoutes.HandleFunc("/notifications/unread-count", handlers.GetUnreadCountHandler).Methods("GET")
+	authRoutes.HandleFunc("/notifications/stream", handlers.NotificationStreamHandler).Methods("GET")
 	authRoutes.HandleFunc("/notifications/send", handlers.SendNotificationHandler).Methods("POST") // <== Add this!
-	// authRoutes.HandleFunc("/notifications/read", handlers.MarkNotificationsAsReadHandler).Methods("PUT")
+	authRoutes.HandleFunc("/notifications/settings", handlers.GetNotificationSettingsHandler).Methods("GET")
+	authRoutes.HandleFunc("/notifications/settings", handlers.UpdateNotificationSettingsHandler).Methods("PUT")
+	authRoutes.HandleFunc("/notifications/preferences", handlers.GetNotificationPreferencesHandler).Methods("GET")
+	authRoutes.HandleFunc("/notifications/preferences", handlers.UpdateNotificationPreferencesHandler).Methods("PUT")
+	authRoutes.HandleFunc("/notifications/mutes", handlers.GetNotificationMutesHandler).Methods("GET")
+	authRoutes.HandleFunc("/notifications/mutes", handlers.MuteNotificationsHandler).Methods("POST")
+	authRoutes.HandleFunc("/notifications/mutes/{entityType:[a-z_]+}/{id:[0-9]+}", handlers.UnmuteNotificationsHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/notifications/read", handlers.MarkNotificationsAsReadHandler).Methods("PUT")
+	authRoutes.HandleFunc("/notifications/{id:[0-9]+}/read", handlers.MarkNotificationReadHandler).Methods("PUT")
+	authRoutes.HandleFunc("/notifications/{id:[0-9]+}", handlers.DeleteNotificationHandler).Methods("DELETE")
+	authRoutes.HandleFunc("/notifications/{id:[0-9]+}/accept", handlers.AcceptNotificationHandler).Methods("POST")
+	authRoutes.HandleFunc("/notifications/{id:[0-9]+}/decline", handlers.DeclineNotificationHandler).Methods("POST")
+
+	// ✅ Followers
+	authRoutes.HandleFunc("/users/{id:[0-9]+}/follow", handlers.FollowUserHandler).Methods("POST")
 
 	// ✅ Private Chat
 	authRoutes.HandleFunc("/chat/send", handlers.SendMessageHandler).Methods("POST")
 	authRoutes.HandleFunc("/chat/history", handlers.GetChatHistoryHandler).Methods("GET")
 
+	// ✅ Background jobs
+	go runRSVPDigests(db, rsvpDigestInterval)
+	go runEventReminders(db, eventReminderInterval)
+	go runEmailDigests(db, mailer.FromEnv(), emailDigestCheckInterval)
+	go runWebhookDeliveries(db, webhooks.NewSender(), webhookDeliveryInterval)
+
 	log.Println("✅ Server running on :8080")
 	http.ListenAndServe(":8080", r)
 }
@@ -121,55 +193,3 @@ func WebSocketChatHandler(w http.ResponseWriter, r *http.Request) {
 
 	chatManager.HandleChatConnection(conn, userID)
 }
-
-// ✅ WebSocket Handler for Group Chat
-func WebSocketGroupChatHandler(w http.ResponseWriter, r *http.Request) {
-	userID := middlewares.GetUserIDFromSession(r)
-	if userID == 0 {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	groupID, err := strconv.Atoi(r.URL.Query().Get("group_id"))
-	if err != nil || groupID == 0 {
-		http.Error(w, "Invalid group ID", http.StatusBadRequest)
-		return
-	}
-
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		http.Error(w, "Failed to upgrade WebSocket", http.StatusInternalServerError)
-		return
-	}
-
-	groupChatManager.JoinGroupChat(conn, groupID, userID)
-}
-
-// ✅ WebSocket Handler for Notifications
-func WebSocketNotificationHandler(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.URL.Query().Get("user_id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
-	}
-
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		http.Error(w, "Failed to upgrade WebSocket", http.StatusInternalServerError)
-		return
-	}
-	defer conn.Close()
-
-	// ✅ Store WebSocket connection for notifications
-	notification := websockets.Notification{
-		Type:    "notification",
-		UserID:  userID,
-		Message: "Welcome to the notification system!",
-	}
-
-	err = conn.WriteJSON(notification)
-	if err != nil {
-		http.Error(w, "Failed to send notification", http.StatusInternalServerError)
-	}
-}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"social-network/internal/repositories"
+	websockets "social-network/internal/websocket"
+)
+
+// rsvpDigestInterval is how often event creators who opted in receive their RSVP digest
+const rsvpDigestInterval = time.Hour
+
+// runRSVPDigests periodically sends each opted-in creator one notification summarising new RSVPs
+func runRSVPDigests(db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sendRSVPDigests(db)
+	}
+}
+
+// sendRSVPDigests sends a digest to every subscriber who got new RSVPs since their last one
+func sendRSVPDigests(db *sql.DB) {
+	repo := repositories.NewNotificationSettingsRepository(db)
+	userIDs, err := repo.GetRSVPDigestSubscribers()
+	if err != nil {
+		log.Println("❌ Failed to load RSVP digest subscribers:", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		activity, err := repo.TakeRSVPDigest(userID)
+		if err != nil {
+			log.Printf("❌ Failed to build RSVP digest for User %d: %v", userID, err)
+			continue
+		}
+		if len(activity) == 0 {
+			continue
+		}
+
+		lines := make([]string, len(activity))
+		for i, a := range activity {
+			lines[i] = a.Describe()
+		}
+		message := fmt.Sprintf("%d new RSVPs to your events: %s", len(activity), strings.Join(lines, "; "))
+		websockets.SendNotification(userID, "event_rsvp_digest", message)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+	"social-network/internal/webhooks"
+)
+
+// webhookDeliveryInterval is how often the webhook queue is checked for due deliveries
+const webhookDeliveryInterval = 5 * time.Second
+
+// webhookDeliveryBatch caps how many deliveries are attempted per tick
+const webhookDeliveryBatch = 50
+
+// webhookDeliveriesPerWebhook caps how many of one webhook's deliveries are attempted per tick
+const webhookDeliveriesPerWebhook = 10
+
+// webhookDeliveryWorkers is how many webhooks are sent to at the same time. Each webhook's
+// deliveries are sent one at a time, in order, so a receiver never has more than one in flight.
+const webhookDeliveryWorkers = 8
+
+// runWebhookDeliveries works through the webhook delivery queue now and then on every tick.
+// The queue lives in the database, so pending retries survive a restart.
+func runWebhookDeliveries(db *sql.DB, sender *webhooks.Sender, interval time.Duration) {
+	sendWebhookDeliveries(db, sender, time.Now().UTC())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		sendWebhookDeliveries(db, sender, now.UTC())
+	}
+}
+
+// sendWebhookDeliveries attempts the due deliveries once each and records the outcomes, sending
+// to different webhooks in parallel on a bounded number of workers
+func sendWebhookDeliveries(db *sql.DB, sender *webhooks.Sender, now time.Time) {
+	repo := repositories.NewWebhookRepository(db)
+
+	deliveries, err := repo.GetDueDeliveries(now, webhookDeliveriesPerWebhook, webhookDeliveryBatch)
+	if err != nil {
+		log.Println("❌ Failed to load due webhook deliveries:", err)
+		return
+	}
+
+	// Group the deliveries by webhook, keeping the queue order within each group
+	var webhookIDs []int
+	byWebhook := make(map[int][]*models.WebhookDelivery)
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		if _, ok := byWebhook[delivery.WebhookID]; !ok {
+			webhookIDs = append(webhookIDs, delivery.WebhookID)
+		}
+		byWebhook[delivery.WebhookID] = append(byWebhook[delivery.WebhookID], delivery)
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < webhookDeliveryWorkers && i < len(webhookIDs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for webhookID := range queue {
+				sendToWebhook(repo, sender, webhookID, byWebhook[webhookID])
+			}
+		}()
+	}
+	for _, webhookID := range webhookIDs {
+		queue <- webhookID
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// sendToWebhook sends one webhook's due deliveries in order. It stops at the first failure and
+// leaves the rest queued, so a receiver that is down costs one timeout per tick rather than one
+// per delivery.
+func sendToWebhook(repo *repositories.WebhookRepository, sender *webhooks.Sender, webhookID int, deliveries []*models.WebhookDelivery) {
+	webhook, err := repo.GetWebhookByID(webhookID)
+	if err != nil {
+		log.Printf("❌ Failed to load Webhook %d: %v", webhookID, err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		attempt, succeeded := sender.Send(webhook, delivery)
+		if err := repo.RecordAttempt(delivery, attempt, succeeded); err != nil {
+			log.Printf("❌ Failed to record attempt for webhook delivery %d: %v", delivery.ID, err)
+			return
+		}
+
+		switch delivery.Status {
+		case models.WebhookDeliveryDelivered:
+			log.Printf("🔗 Delivered %s to Webhook %d (delivery %d)", delivery.Event, webhook.ID, delivery.ID)
+		case models.WebhookDeliveryFailed:
+			log.Printf("❌ Gave up on webhook delivery %d after %d attempts: %s", delivery.ID, delivery.Attempts, attempt.Error)
+		default:
+			log.Printf("⚠️ Webhook delivery %d failed (%s), retrying at %s", delivery.ID, attempt.Error, delivery.NextAttemptAt.Format(time.RFC3339))
+		}
+		if !succeeded {
+			return
+		}
+	}
+}
@@ -24,6 +24,9 @@ func GetDB() *sql.DB {
 		os.Mkdir("./data", os.ModePerm)
 	}
 
+	// Foreign key enforcement is a per-connection PRAGMA that migrations switch off for table
+	// rebuilds, and few tables declare ON DELETE CASCADE, so repositories delete dependent rows
+	// themselves
 	var err error
 	db, err = sql.Open("sqlite3", "./data/forum.db?_busy_timeout=10000&_journal_mode=WAL&_locking_mode=NORMAL")
 	if err != nil {
@@ -58,8 +61,27 @@ func applyMigrations() error {
 		return fmt.Errorf("failed to read migrations directory: %v", err)
 	}
 
+	// ✅ Track applied migrations so table rebuilds only run once
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version TEXT PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".up.sql") {
+			var applied bool
+			err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)", file.Name()).Scan(&applied)
+			if err != nil {
+				return fmt.Errorf("failed to check migration %s: %v", file.Name(), err)
+			}
+			if applied {
+				continue
+			}
+
 			migrationPath := filepath.Join(absPath, file.Name())
 			migrationSQL, err := os.ReadFile(migrationPath)
 			if err != nil {
@@ -70,6 +92,9 @@ func applyMigrations() error {
 			if err != nil {
 				return fmt.Errorf("failed to execute migration %s: %v", file.Name(), err)
 			}
+			if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", file.Name()); err != nil {
+				return fmt.Errorf("failed to record migration %s: %v", file.Name(), err)
+			}
 			fmt.Println("🔹 Applied migration:", file.Name())
 		}
 	}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"social-network/internal/config"
+	"social-network/internal/middlewares"
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+// icsTimeLayout is the iCalendar UTC date-time format. Event dates are stored in UTC,
+// so every time is written with a Z suffix and calendar clients convert to local time.
+const icsTimeLayout = "20060102T150405Z"
+
+// icsEscaper escapes TEXT values as required by RFC 5545
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// writeICSLine writes a content line, folding it at 75 octets without splitting UTF-8 characters
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// icsRecurrenceRule renders a recurrence as an RRULE value
+func icsRecurrenceRule(rule *models.EventRecurrence) string {
+	value := fmt.Sprintf("FREQ=%s;INTERVAL=%d", strings.ToUpper(rule.Freq), rule.Interval)
+	if rule.Until != "" {
+		if until, err := models.ParseEventDate(rule.Until); err == nil {
+			value += ";UNTIL=" + until.Format(icsTimeLayout)
+		}
+	}
+	if rule.Count > 0 {
+		value += fmt.Sprintf(";COUNT=%d", rule.Count)
+	}
+	return value
+}
+
+// icsUID identifies an event in calendars. Occurrences listed on their own (as in the
+// subscription feed, which only holds the occurrences a user answered) get their own UID.
+func icsUID(event *models.GroupEvent) string {
+	if event.OccurrenceDate == "" {
+		return fmt.Sprintf("group-event-%d@social-network", event.ID)
+	}
+	start, _ := models.ParseEventDate(event.OccurrenceDate)
+	return fmt.Sprintf("group-event-%d-%s@social-network", event.ID, start.Format(icsTimeLayout))
+}
+
+// writeICSEvent writes one VEVENT; events with an unreadable date are skipped. A series is
+// written with its RRULE and EXDATEs; with asOverride, an occurrence is written as a change
+// to its series (same UID plus RECURRENCE-ID).
+func writeICSEvent(b *strings.Builder, event *models.GroupEvent, stamp time.Time, asOverride bool) {
+	start, err := event.StartTime()
+	if err != nil {
+		log.Printf("⚠️ Skipping event %d in calendar: %v", event.ID, err)
+		return
+	}
+
+	writeICSLine(b, "BEGIN:VEVENT")
+	if asOverride {
+		series := *event
+		series.OccurrenceDate = ""
+		writeICSLine(b, "UID:"+icsUID(&series))
+		recurrenceID, _ := models.ParseEventDate(event.OccurrenceDate)
+		writeICSLine(b, "RECURRENCE-ID:"+recurrenceID.Format(icsTimeLayout))
+	} else {
+		writeICSLine(b, "UID:"+icsUID(event))
+	}
+	writeICSLine(b, "DTSTAMP:"+stamp.Format(icsTimeLayout))
+	writeICSLine(b, "DTSTART:"+start.Format(icsTimeLayout))
+	writeICSLine(b, "DTEND:"+start.Add(models.DefaultEventDuration).Format(icsTimeLayout))
+	if event.Recurrence != nil && event.OccurrenceDate == "" {
+		writeICSLine(b, "RRULE:"+icsRecurrenceRule(event.Recurrence))
+		for _, exception := range event.Recurrence.Exceptions {
+			if t, err := models.ParseEventDate(exception); err == nil {
+				writeICSLine(b, "EXDATE:"+t.Format(icsTimeLayout))
+			}
+		}
+	}
+	writeICSLine(b, fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+	writeICSLine(b, "SUMMARY:"+icsEscaper.Replace(event.Title))
+	writeICSLine(b, "DESCRIPTION:"+icsEscaper.Replace(event.Description))
+	if event.UpdatedAt != nil {
+		writeICSLine(b, "LAST-MODIFIED:"+event.UpdatedAt.UTC().Format(icsTimeLayout))
+	}
+	if event.CancelledAt != nil {
+		writeICSLine(b, "STATUS:CANCELLED")
+	} else {
+		writeICSLine(b, "STATUS:CONFIRMED")
+	}
+	writeICSLine(b, "END:VEVENT")
+}
+
+// renderCalendar builds a VCALENDAR document holding the given events, followed by
+// changed occurrences of recurring events among them
+func renderCalendar(name string, events []models.GroupEvent, overrides []models.GroupEvent) string {
+	var b strings.Builder
+	stamp := time.Now().UTC()
+
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//social-network//group events//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+icsEscaper.Replace(name))
+	for i := range events {
+		writeICSEvent(&b, &events[i], stamp, false)
+	}
+	for i := range overrides {
+		writeICSEvent(&b, &overrides[i], stamp, true)
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// writeCalendar sends an iCalendar document
+func writeCalendar(w http.ResponseWriter, filename, body string) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body))
+}
+
+// GetEventICSHandler exports a single group event as an .ics file
+func GetEventICSHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventID := pathID(r, "eventID")
+	if eventID == 0 {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	event, ok := loadVisibleEvent(w, db, userID, eventID)
+	if !ok {
+		return
+	}
+
+	var changed []models.GroupEvent
+	if event.Recurrence != nil {
+		overrides, err := repositories.NewGroupEventRepository(db).GetOccurrenceOverrides(eventID)
+		if err != nil {
+			http.Error(w, "Failed to retrieve event", http.StatusInternalServerError)
+			return
+		}
+		for _, override := range overrides {
+			start, err := models.ParseEventDate(override.OccurrenceDate)
+			if err != nil || override.Excluded {
+				continue
+			}
+			changed = append(changed, event.AtOccurrence(start, &override))
+		}
+	}
+
+	writeCalendar(w, fmt.Sprintf("event-%d.ics", eventID), renderCalendar(event.Title, []models.GroupEvent{*event}, changed))
+}
+
+// GetCalendarTokenHandler returns the caller's calendar feed URL, creating it on first use
+func GetCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := repositories.NewCalendarTokenRepository(config.GetDB()).GetOrCreateToken(userID)
+	if err != nil {
+		log.Println("❌ Error creating calendar token:", err)
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": token, "feed_url": "/calendar/" + token + ".ics"})
+}
+
+// ResetCalendarTokenHandler replaces the caller's feed token, invalidating the old feed URL
+func ResetCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := repositories.NewCalendarTokenRepository(config.GetDB()).ResetToken(userID)
+	if err != nil {
+		log.Println("❌ Error resetting calendar token:", err)
+		http.Error(w, "Failed to reset calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": token, "feed_url": "/calendar/" + token + ".ics"})
+}
+
+// CalendarFeedHandler serves a user's subscription feed. Calendar apps can't send session
+// cookies, so the secret token in the URL is the only credential.
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	db := config.GetDB()
+	userID, err := repositories.NewCalendarTokenRepository(db).GetUserIDByToken(token)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := repositories.NewGroupEventRepository(db).GetCalendarEvents(userID)
+	if err != nil {
+		log.Println("❌ Error loading calendar events:", err)
+		http.Error(w, "Failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	writeCalendar(w, "events.ics", renderCalendar("My group events", events, nil))
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"social-network/internal/models"
+)
+
+// icsLines unfolds a calendar and splits it into content lines
+func icsLines(t *testing.T, calendar string) []string {
+	t.Helper()
+	if !strings.HasSuffix(calendar, "\r\n") {
+		t.Fatalf("calendar doesn't end with CRLF: %q", calendar)
+	}
+	unfolded := strings.ReplaceAll(calendar, "\r\n ", "")
+	return strings.Split(strings.TrimSuffix(unfolded, "\r\n"), "\r\n")
+}
+
+func hasLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWriteICSLineFolding(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Meetup"},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"76 octets", "SUMMARY:" + strings.Repeat("a", 68)},
+		{"several folds", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30)},
+		{"multi-byte characters across the fold", "SUMMARY:" + strings.Repeat("é", 40) + strings.Repeat("日本", 30)},
+		{"emoji", "DESCRIPTION:" + strings.Repeat("🎉 party ", 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeICSLine(&b, tt.line)
+			folded := b.String()
+
+			physical := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+			for i, line := range physical {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d octets long: %q", i, len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 character: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d doesn't start with a space: %q", i, line)
+				}
+			}
+			if len(tt.line) <= 75 && len(physical) != 1 {
+				t.Errorf("a %d octet line was folded into %d lines", len(tt.line), len(physical))
+			}
+			if got := icsLines(t, folded); len(got) != 1 || got[0] != tt.line {
+				t.Errorf("unfolded = %q, want %q", got, tt.line)
+			}
+		})
+	}
+}
+
+func TestICSEscaper(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"Board games", "Board games"},
+		{"Pizza, drinks; games", `Pizza\, drinks\; games`},
+		{`C:\games`, `C:\\games`},
+		{"Line one\nLine two", `Line one\nLine two`},
+		{"Line one\r\nLine two", `Line one\nLine two`},
+		{`\,`, `\\\,`},
+	}
+	for _, tt := range tests {
+		if got := icsEscaper.Replace(tt.value); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRenderCalendarEvent(t *testing.T) {
+	events := []models.GroupEvent{{
+		ID:          7,
+		Title:       "Games night, round 2",
+		Description: "Bring snacks; we provide\nthe board games",
+		EventDate:   "2027-03-05T19:30:00+02:00",
+		Sequence:    3,
+	}}
+
+	lines := icsLines(t, renderCalendar("Games; club", events, nil))
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Errorf("calendar isn't wrapped in VCALENDAR: %q", lines)
+	}
+	for _, want := range []string{
+		`X-WR-CALNAME:Games\; club`,
+		"BEGIN:VEVENT",
+		"UID:group-event-7@social-network",
+		"DTSTART:20270305T173000Z",
+		"DTEND:20270305T183000Z",
+		"SEQUENCE:3",
+		`SUMMARY:Games night\, round 2`,
+		`DESCRIPTION:Bring snacks\; we provide\nthe board games`,
+		"STATUS:CONFIRMED",
+		"END:VEVENT",
+	} {
+		if !hasLine(lines, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "RRULE:") {
+			t.Errorf("one-off event has a recurrence rule: %q", line)
+		}
+	}
+}
+
+func TestRenderCalendarSeries(t *testing.T) {
+	series := models.GroupEvent{
+		ID:        4,
+		Title:     "Weekly meetup",
+		EventDate: "2027-01-04 10:00",
+		Recurrence: &models.EventRecurrence{
+			Freq:       models.RecurrenceWeekly,
+			Interval:   2,
+			Until:      "2027-06-01T00:00:00+02:00",
+			Exceptions: []string{"2027-01-18T10:00:00Z"},
+		},
+	}
+	occurrence, _ := models.ParseEventDate("2027-02-01T10:00:00Z")
+	movedDate := "2027-02-01T12:00:00Z"
+	moved := series.AtOccurrence(occurrence, &models.EventOccurrenceOverride{EventDate: &movedDate})
+
+	lines := icsLines(t, renderCalendar("Meetups", []models.GroupEvent{series}, []models.GroupEvent{moved}))
+	for _, want := range []string{
+		"DTSTART:20270104T100000Z",
+		"DTEND:20270104T110000Z",
+		"RRULE:FREQ=WEEKLY;INTERVAL=2;UNTIL=20270531T220000Z",
+		"EXDATE:20270118T100000Z",
+		"RECURRENCE-ID:20270201T100000Z",
+		"DTSTART:20270201T120000Z",
+	} {
+		if !hasLine(lines, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+
+	uids := 0
+	for _, line := range lines {
+		if line == "UID:group-event-4@social-network" {
+			uids++
+		}
+	}
+	if uids != 2 {
+		t.Errorf("the changed occurrence doesn't share its series' UID:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestRenderCalendarSkipsUnreadableDates(t *testing.T) {
+	events := []models.GroupEvent{{ID: 1, Title: "Broken", EventDate: "tomorrow"}}
+
+	lines := icsLines(t, renderCalendar("Events", events, nil))
+	if hasLine(lines, "BEGIN:VEVENT") {
+		t.Errorf("calendar includes an event without a valid date:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestICSRecurrenceRuleCount(t *testing.T) {
+	rule := &models.EventRecurrence{Freq: models.RecurrenceMonthly, Interval: 1, Count: 6}
+	if got := icsRecurrenceRule(rule); got != "FREQ=MONTHLY;INTERVAL=1;COUNT=6" {
+		t.Errorf("icsRecurrenceRule() = %q", got)
+	}
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
 	"social-network/internal/models"
 	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
 	"strconv"
+	"strings"
 )
 
 func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
@@ -24,16 +28,61 @@ func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if comment.Content == "" || comment.PostID == 0 {
-		http.Error(w, "Comment content and post ID are required", http.StatusBadRequest)
-		return
-	}
-
 	comment.UserID = userID
+	comment.Depth = 0
 
 	db := config.GetDB()
 	commentRepo := repositories.NewCommentRepository(db)
 
+	// ✅ Replies inherit the parent's post and sit one level below it
+	var parent *models.Comment
+	if comment.ParentID != 0 {
+		var err error
+		parent, err = commentRepo.GetCommentByID(comment.ParentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Parent comment not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Println("Error loading parent comment:", err)
+			http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+			return
+		}
+		if comment.PostID == 0 && comment.GroupPostID == 0 {
+			comment.PostID, comment.GroupPostID = parent.PostID, parent.GroupPostID
+		}
+		if comment.PostID != parent.PostID || comment.GroupPostID != parent.GroupPostID {
+			http.Error(w, "Parent comment belongs to a different post", http.StatusBadRequest)
+			return
+		}
+		if parent.Depth >= models.MaxCommentDepth {
+			http.Error(w, fmt.Sprintf("Replies can only be nested %d levels deep", models.MaxCommentDepth), http.StatusBadRequest)
+			return
+		}
+		comment.Depth = parent.Depth + 1
+	}
+
+	if comment.Content == "" || (comment.PostID == 0) == (comment.GroupPostID == 0) {
+		http.Error(w, "Comment content and either post ID or group post ID are required", http.StatusBadRequest)
+		return
+	}
+
+	// ✅ Only people who can see a post may comment on it, and group posts can't be commented
+	// on in archived groups
+	if !requireCommentsVisible(w, db, userID, comment.PostID, comment.GroupPostID) {
+		return
+	}
+	if comment.GroupPostID != 0 {
+		groupID, err := groupIDForContent(db, 0, comment.GroupPostID)
+		if err != nil {
+			http.Error(w, "Group post not found", http.StatusNotFound)
+			return
+		}
+		if !requireGroupNotArchived(w, db, groupID) {
+			return
+		}
+	}
+
 	err := commentRepo.AddComment(&comment)
 	if err != nil {
 		log.Println("Error adding comment:", err)
@@ -41,8 +90,78 @@ func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyComment(db, &comment, parent)
+
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Comment added successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Comment added successfully", "comment_id": comment.ID})
+}
+
+// notifyComment tells the author of a post or group post that someone else commented on it.
+// For a reply, the parent comment's author is told instead if they also wrote the post.
+func notifyComment(db *sql.DB, comment *models.Comment, parent *models.Comment) {
+	if parent != nil && parent.UserID != comment.UserID {
+		notifyReply(db, comment, parent)
+	}
+
+	ownerID, entityType, entityID, err := likedContent(db, comment.PostID, 0, comment.GroupPostID)
+	if err != nil {
+		log.Println("❌ Failed to load commented post for notification:", err)
+		return
+	}
+	if ownerID == comment.UserID || (parent != nil && ownerID == parent.UserID) {
+		return
+	}
+
+	nickname, err := repositories.NewUserRepository(db).GetNickname(comment.UserID)
+	if err != nil {
+		log.Println("❌ Failed to load commenter for notification:", err)
+		return
+	}
+	action := "commented on your " + strings.ReplaceAll(entityType, "_", " ")
+	ws.Notify(models.Notification{
+		UserID:     ownerID,
+		Type:       models.NotificationTypeComment,
+		Message:    fmt.Sprintf("%s %s: %s", nickname, action, excerpt(comment.Content)),
+		ActorID:    comment.UserID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Data:       map[string]interface{}{"comment_id": comment.ID, "action": action},
+	})
+}
+
+// notifyReply tells a comment's author that someone replied to it
+func notifyReply(db *sql.DB, reply *models.Comment, parent *models.Comment) {
+	nickname, err := repositories.NewUserRepository(db).GetNickname(reply.UserID)
+	if err != nil {
+		log.Println("❌ Failed to load replier for notification:", err)
+		return
+	}
+	action := "replied to your comment"
+	data := map[string]interface{}{"comment_id": reply.ID, "action": action}
+	if reply.PostID != 0 {
+		data["post_id"] = reply.PostID
+	} else {
+		data["group_post_id"] = reply.GroupPostID
+	}
+	ws.Notify(models.Notification{
+		UserID:     parent.UserID,
+		Type:       models.NotificationTypeReply,
+		Message:    fmt.Sprintf("%s %s: %s", nickname, action, excerpt(reply.Content)),
+		ActorID:    reply.UserID,
+		EntityType: models.NotificationEntityComment,
+		EntityID:   parent.ID,
+		Data:       data,
+	})
+}
+
+// excerpt shortens user content for notification messages
+func excerpt(content string) string {
+	const maxRunes = 80
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= maxRunes {
+		return string(runes)
+	}
+	return string(runes[:maxRunes-1]) + "…"
 }
 func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
     userID := middlewares.GetUserIDFromSession(r)
@@ -79,24 +198,94 @@ func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(map[string]string{"message": "Comment updated successfully"})
 }
 
+// Page sizes for a post's comments
+const (
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
+)
+
+// GetCommentsForPostHandler returns a page of the top-level comments on a post (?post_id=) or
+// group post (?group_post_id=) the viewer may see, each with a preview of its replies. Pages hold
+// ?limit= comments (default 20, max 100) in ?order=oldest (default) or ?order=newest order; pass
+// the ID of the last comment shown as ?after_id= for the next page.
 func GetCommentsForPostHandler(w http.ResponseWriter, r *http.Request) {
-    postID, err := strconv.Atoi(r.URL.Query().Get("post_id"))
-    if err != nil || postID == 0 {
-        http.Error(w, "Invalid post ID", http.StatusBadRequest)
-        return
-    }
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-    db := config.GetDB()
-    repo := repositories.NewCommentRepository(db)
+	query := r.URL.Query()
+	var postID, groupPostID int
+	var err error
+	if value := query.Get("group_post_id"); value != "" {
+		groupPostID, err = strconv.Atoi(value)
+		if err != nil || groupPostID == 0 {
+			http.Error(w, "Invalid group post ID", http.StatusBadRequest)
+			return
+		}
+	} else {
+		postID, err = strconv.Atoi(query.Get("post_id"))
+		if err != nil || postID == 0 {
+			http.Error(w, "Invalid post ID", http.StatusBadRequest)
+			return
+		}
+	}
 
-    comments, err := repo.GetCommentsForPost(postID)
-    if err != nil {
-        log.Println("Error retrieving comments:", err)
-        http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
-        return
-    }
+	page := repositories.CommentPage{Limit: defaultCommentPageSize}
+	if value := query.Get("limit"); value != "" {
+		page.Limit, err = strconv.Atoi(value)
+		if err != nil || page.Limit < 1 || page.Limit > maxCommentPageSize {
+			http.Error(w, fmt.Sprintf("Limit must be between 1 and %d", maxCommentPageSize), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("after_id"); value != "" {
+		page.AfterID, err = strconv.Atoi(value)
+		if err != nil || page.AfterID < 0 {
+			http.Error(w, "Invalid after_id", http.StatusBadRequest)
+			return
+		}
+	}
+	switch query.Get("order") {
+	case "", "oldest":
+	case "newest":
+		page.NewestFirst = true
+	default:
+		http.Error(w, "Order must be oldest or newest", http.StatusBadRequest)
+		return
+	}
 
-    json.NewEncoder(w).Encode(comments)
+	db := config.GetDB()
+	if !requireCommentsVisible(w, db, userID, postID, groupPostID) {
+		return
+	}
+
+	// Fetch one extra comment to tell whether there's another page
+	repo := repositories.NewCommentRepository(db)
+	limit := page.Limit
+	page.Limit++
+	var comments []models.Comment
+	if groupPostID != 0 {
+		comments, err = repo.GetCommentsForGroupPost(groupPostID, userID, page)
+	} else {
+		comments, err = repo.GetCommentsForPost(postID, userID, page)
+	}
+	if err != nil {
+		log.Println("Error retrieving comments:", err)
+		http.Error(w, "Failed to retrieve comments", http.StatusInternalServerError)
+		return
+	}
+	hasMore := len(comments) > limit
+	if hasMore {
+		comments = comments[:limit]
+	}
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"comments": comments, "has_more": hasMore})
 }
 
 func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
@@ -125,3 +314,111 @@ func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
     w.WriteHeader(http.StatusOK)
     json.NewEncoder(w).Encode(map[string]string{"message": "Comment deleted successfully"})
 }
+
+// requireCommentsVisible writes an error and returns false unless the user may see the post, or is
+// a member of the group of the group post, whose comments they're asking for
+func requireCommentsVisible(w http.ResponseWriter, db *sql.DB, userID, postID, groupPostID int) bool {
+	if groupPostID != 0 {
+		groupID, err := groupIDForContent(db, 0, groupPostID)
+		if err != nil {
+			http.Error(w, "Group post not found", http.StatusNotFound)
+			return false
+		}
+		return requireGroupMember(w, db, userID, groupID)
+	}
+
+	visible, err := repositories.NewPostRepository(db).CanViewPost(userID, postID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		log.Println("❌ Error checking post visibility:", err)
+		http.Error(w, "Failed to verify access to the post", http.StatusInternalServerError)
+		return false
+	}
+	if !visible {
+		http.Error(w, "You don't have permission to view this post", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Page sizes for a comment's replies
+const (
+	defaultReplyPageSize = 10
+	maxReplyPageSize     = 50
+)
+
+// GetCommentRepliesHandler loads more of a comment's direct replies, oldest first, for the
+// "load more replies" link. Pass the ID of the last reply shown as ?after_id= and optionally
+// ?limit= (default 10, max 50); each reply comes with a preview of its own replies.
+func GetCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	commentID := pathID(r, "id")
+	if commentID == 0 {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, afterID := defaultReplyPageSize, 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReplyPageSize {
+			http.Error(w, fmt.Sprintf("Limit must be between 1 and %d", maxReplyPageSize), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	if value := r.URL.Query().Get("after_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid after_id", http.StatusBadRequest)
+			return
+		}
+		afterID = parsed
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewCommentRepository(db)
+
+	comment, err := repo.GetCommentByID(commentID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error retrieving comment:", err)
+		http.Error(w, "Failed to retrieve replies", http.StatusInternalServerError)
+		return
+	}
+	if !requireCommentsVisible(w, db, userID, comment.PostID, comment.GroupPostID) {
+		return
+	}
+
+	replies, err := repo.GetReplies(commentID, userID, afterID, limit+1)
+	if err != nil {
+		log.Println("Error retrieving replies:", err)
+		http.Error(w, "Failed to retrieve replies", http.StatusInternalServerError)
+		return
+	}
+	hasMore := len(replies) > limit
+	if hasMore {
+		replies = replies[:limit]
+	}
+	if replies == nil {
+		replies = []models.Comment{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"replies":     replies,
+		"reply_count": comment.ReplyCount,
+		"has_more":    hasMore,
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,6 +10,7 @@ import (
 
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
+	"social-network/internal/models"
 	"social-network/internal/repositories"
 	"social-network/internal/websocket"
 )
@@ -34,28 +36,115 @@ func RSVPEventHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if !models.ValidRSVPChoice(requestBody.Status) {
+		http.Error(w, "Status must be going, not going, interested or maybe", http.StatusBadRequest)
+		return
+	}
 
 	db := config.GetDB()
+	event, err := repositories.NewGroupEventRepository(db).GetEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if event.CancelledAt != nil {
+		http.Error(w, "This event has been cancelled", http.StatusConflict)
+		return
+	}
+	if !requireGroupMember(w, db, userID, event.GroupID) {
+		return
+	}
+	event, ok := resolveOccurrence(w, r, db, event, true)
+	if !ok {
+		return
+	}
+
 	eventRepo := repositories.NewEventRSVPRepository(db) // ✅ FIXED: Using the correct repository
 
-	// ✅ Update RSVP in the database
-	err = eventRepo.RSVPToEvent(eventID, userID, requestBody.Status)
+	// ✅ Update RSVP in the database; "going" may be turned into "waitlisted" when the event is full
+	status, promoted, err := eventRepo.RSVPToEvent(eventID, event.OccurrenceDate, userID, requestBody.Status)
 	if err != nil {
 		log.Println("❌ Failed to RSVP:", err)
 		http.Error(w, "Failed to RSVP", http.StatusInternalServerError)
 		return
 	}
 
-	// ✅ Send WebSocket notification
-	message := fmt.Sprintf("User %d has RSVP'd as %s to your event", userID, requestBody.Status)
-	websocket.SendNotification(userID, "event_rsvp", message)
+	notifyEventCreatorOfRSVP(db, event, userID, status)
+	notifyPromotedAttendees(event, promoted)
+	queueRSVPWebhook(db, event, userID, requestBody.Status, status)
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "RSVP updated successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "RSVP updated successfully", "status": status})
+}
+
+// queueRSVPWebhook queues a group_event.rsvp event; status differs from the choice when a
+// "going" response was waitlisted
+func queueRSVPWebhook(db *sql.DB, event *models.GroupEvent, userID int, choice, status string) {
+	data := event.NotificationData()
+	data["event_id"] = event.ID
+	data["user_id"] = userID
+	data["choice"] = choice
+	data["status"] = status
+	queueWebhookEvent(db, event.GroupID, models.WebhookEventEventRSVP, data)
+}
+
+// notifyEventCreatorOfRSVP tells the event creator about a response, unless they asked for a digest
+func notifyEventCreatorOfRSVP(db *sql.DB, event *models.GroupEvent, responderID int, status string) {
+	if event.CreatorID == responderID {
+		return
+	}
+
+	settings, err := repositories.NewNotificationSettingsRepository(db).GetSettings(event.CreatorID)
+	if err != nil {
+		log.Println("❌ Failed to load notification settings:", err)
+		return
+	}
+	if settings.RSVPDigest {
+		return // Picked up by the next RSVP digest
+	}
+
+	nickname, err := repositories.NewUserRepository(db).GetNickname(responderID)
+	if err != nil {
+		log.Println("❌ Failed to load responder nickname:", err)
+		return
+	}
+
+	activity := models.RSVPActivity{EventID: event.ID, EventTitle: event.Title, UserID: responderID, Nickname: nickname, Status: status}
+	data := event.NotificationData()
+	data["status"] = status
+	websocket.Notify(models.Notification{
+		UserID:     event.CreatorID,
+		Type:       "event_rsvp",
+		Message:    activity.Describe(),
+		ActorID:    responderID,
+		EntityType: models.NotificationEntityEvent,
+		EntityID:   event.ID,
+		Data:       data,
+	})
+}
+
+// notifyPromotedAttendees tells users who moved off the waitlist that they now have a spot
+func notifyPromotedAttendees(event *models.GroupEvent, userIDs []int) {
+	for _, userID := range userIDs {
+		websocket.Notify(models.Notification{
+			UserID:     userID,
+			Type:       "event_waitlist_promoted",
+			Message:    fmt.Sprintf("A spot opened up for \"%s\" on %s. You're now going!", event.Title, event.EventDate),
+			EntityType: models.NotificationEntityEvent,
+			EntityID:   event.ID,
+			Data:       event.NotificationData(),
+		})
+	}
 }
 
 // GetRSVPCountHandler returns the number of users attending an event
 func GetRSVPCountHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	eventID, err := strconv.Atoi(r.URL.Query().Get("event_id"))
 	if err != nil || eventID == 0 {
 		http.Error(w, "Invalid event ID", http.StatusBadRequest)
@@ -63,9 +152,22 @@ func GetRSVPCountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := config.GetDB()
+	event, err := repositories.NewGroupEventRepository(db).GetEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if !requireGroupVisible(w, db, userID, event.GroupID) {
+		return
+	}
+	event, ok := resolveOccurrence(w, r, db, event, true)
+	if !ok {
+		return
+	}
+
 	repo := repositories.NewEventRSVPRepository(db)
 
-	count, err := repo.GetRSVPCount(eventID)
+	count, err := repo.GetRSVPCount(eventID, event.OccurrenceDate)
 	if err != nil {
 		log.Println("Error retrieving RSVP count:", err)
 		http.Error(w, "Failed to retrieve RSVP count", http.StatusInternalServerError)
@@ -75,3 +177,38 @@ func GetRSVPCountHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]int{"going_count": count})
 }
+
+// GetRSVPBreakdownHandler returns how many users gave each RSVP status for an event
+func GetRSVPBreakdownHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventID, err := strconv.Atoi(r.URL.Query().Get("event_id"))
+	if err != nil || eventID == 0 {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	event, ok := loadVisibleEvent(w, db, userID, eventID)
+	if !ok {
+		return
+	}
+	event, ok = resolveOccurrence(w, r, db, event, true)
+	if !ok {
+		return
+	}
+
+	counts, err := repositories.NewEventRSVPRepository(db).GetRSVPBreakdown(eventID, event.OccurrenceDate)
+	if err != nil {
+		log.Println("Error retrieving RSVP breakdown:", err)
+		http.Error(w, "Failed to retrieve RSVP breakdown", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.RSVPBreakdown{EventID: eventID, OccurrenceDate: event.OccurrenceDate, Capacity: event.Capacity, Counts: counts})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"social-network/internal/config"
+	"social-network/internal/middlewares"
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
+)
+
+// FollowUserHandler sends a follow request to another user, who can accept or decline it from
+// the notification it creates
+func FollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	followingID := pathID(r, "id")
+	if followingID == 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if followingID == userID {
+		http.Error(w, "You can't follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	userRepo := repositories.NewUserRepository(db)
+	if _, err := userRepo.GetNickname(followingID); err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println("❌ Error loading user to follow:", err)
+		http.Error(w, "Failed to send follow request", http.StatusInternalServerError)
+		return
+	}
+
+	follow, created, err := repositories.NewFollowRepository(db).RequestFollow(userID, followingID)
+	if err != nil {
+		log.Println("❌ Error creating follow request:", err)
+		http.Error(w, "Failed to send follow request", http.StatusInternalServerError)
+		return
+	}
+
+	if !created {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(follow)
+		return
+	}
+
+	nickname, err := userRepo.GetNickname(userID)
+	if err != nil {
+		log.Println("❌ Error loading follower nickname:", err)
+	}
+	ws.Notify(models.Notification{
+		UserID:     followingID,
+		Type:       models.NotificationTypeFollowRequest,
+		Message:    fmt.Sprintf("%s wants to follow you", nickname),
+		ActorID:    userID,
+		EntityType: models.NotificationEntityFollowRequest,
+		EntityID:   follow.ID,
+		State:      models.NotificationStatePending,
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(follow)
+}
+
+// answerFollowRequest accepts or declines a pending request to follow the user, resolves its
+// notification and tells the requester the outcome
+func answerFollowRequest(db *sql.DB, followID, userID int, accept bool) error {
+	repo := repositories.NewFollowRepository(db)
+	var follow *models.Follow
+	var err error
+	if accept {
+		follow, err = repo.AcceptFollowRequest(followID, userID)
+	} else {
+		follow, err = repo.DeclineFollowRequest(followID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	state, notifType, outcome := models.NotificationStateAccepted, "follow_accepted", "accepted"
+	if !accept {
+		state, notifType, outcome = models.NotificationStateDeclined, "follow_declined", "declined"
+	}
+	resolveRequestNotifications(db, models.NotificationTypeFollowRequest, models.NotificationEntityFollowRequest, followID, follow.FollowerID, state)
+
+	nickname, err := repositories.NewUserRepository(db).GetNickname(userID)
+	if err != nil {
+		log.Println("❌ Error loading nickname for follow response:", err)
+	}
+	ws.Notify(models.Notification{
+		UserID:     follow.FollowerID,
+		Type:       notifType,
+		Message:    fmt.Sprintf("%s %s your follow request", nickname, outcome),
+		ActorID:    userID,
+		EntityType: models.NotificationEntityFollowRequest,
+		EntityID:   followID,
+	})
+	return nil
+}
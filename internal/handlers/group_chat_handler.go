@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -9,14 +10,15 @@ import (
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
 	"social-network/internal/models"
+	"social-network/internal/repositories"
 	ws "social-network/internal/websocket" // ✅ Use alias "ws"
 	"github.com/gorilla/websocket"
 )
 
 
 
-// ✅ Create a Global Instance of GroupChatManager
-var groupChatManager = ws.NewGroupChatManager()
+// ✅ Use the shared GroupChatManager so REST sends reach live sockets
+var groupChatManager = ws.GroupChatHub
 
 // ✅ WebSocket Upgrader (Allows Cross-Origin Requests)
 var upgrader = websocket.Upgrader{
@@ -26,26 +28,13 @@ var upgrader = websocket.Upgrader{
 }
 
 
-// WebSocketGroupChatHandler handles WebSocket connections for group chat
+// WebSocketGroupChatHandler handles WebSocket connections for group chat; only approved
+// members with a session can connect
 func WebSocketGroupChatHandler(w http.ResponseWriter, r *http.Request) {
-	// ✅ Extract user ID from session or query param
 	userID := middlewares.GetUserIDFromSession(r)
-
-	// If session authentication fails, use query params
 	if userID == 0 {
-		userIDParam := r.URL.Query().Get("user_id")
-		parsedID, err := strconv.Atoi(userIDParam)
-		if err != nil {
-			log.Println("❌ Failed to extract userID from query parameters")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		userID = parsedID
-
-		// ✅ Only log this if the session failed
-		log.Printf("⚠️ No session found, but extracted userID %d from query parameters", userID)
-	} else {
-		log.Printf("✅ Verified userID %d from session", userID)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
 	// Get Group ID
@@ -55,6 +44,10 @@ func WebSocketGroupChatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireGroupMember(w, config.GetDB(), userID, groupID) {
+		return
+	}
+
 	// Upgrade to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -69,13 +62,24 @@ func WebSocketGroupChatHandler(w http.ResponseWriter, r *http.Request) {
 
 
 func GetGroupChatHistoryHandler(w http.ResponseWriter, r *http.Request) {
+    userID := middlewares.GetUserIDFromSession(r)
+    if userID == 0 {
+        http.Error(w, "Unauthorized", http.StatusUnauthorized)
+        return
+    }
+
     groupID, err := strconv.Atoi(r.URL.Query().Get("group_id"))
     if err != nil || groupID == 0 {
         http.Error(w, "Invalid group ID", http.StatusBadRequest)
         return
     }
 
+    // Chat history is for members only, in public groups too; see requireGroupVisible
     db := config.GetDB()
+    if !requireGroupMember(w, db, userID, groupID) {
+        return
+    }
+
 	rows, err := db.Query(`SELECT id, group_id, sender_id, content, sent_at FROM group_chat_messages WHERE group_id = ? ORDER BY sent_at ASC`, groupID)
     if err != nil {
         log.Println("❌ Error fetching chat history:", err)
@@ -116,6 +120,10 @@ func SendGroupChatMessageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := config.GetDB()
+	if !requireGroupMember(w, db, userID, req.GroupID) || !requireGroupNotArchived(w, db, req.GroupID) {
+		return
+	}
+
 	_, err := db.Exec("INSERT INTO group_chat_messages (group_id, sender_id, content) VALUES (?, ?, ?)",
 		req.GroupID, userID, req.Content)
 
@@ -133,3 +141,45 @@ func SendGroupChatMessageHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Message sent successfully"})
 }
+
+// DeleteGroupChatMessageHandler removes a chat message; senders can delete their own,
+// members with the manage_chat permission can delete any
+func DeleteGroupChatMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, err := strconv.Atoi(r.URL.Query().Get("message_id"))
+	if err != nil || messageID == 0 {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupChatRepository(db)
+
+	message, err := repo.GetGroupChatMessage(messageID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete message", http.StatusInternalServerError)
+		return
+	}
+
+	if message.SenderID != userID && !requireGroupPermission(w, db, userID, message.GroupID, models.GroupPermManageChat) {
+		return
+	}
+
+	if err := repo.DeleteGroupChatMessage(messageID); err != nil {
+		log.Println("❌ Failed to delete group message:", err)
+		http.Error(w, "Failed to delete message", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Message deleted successfully"})
+}
@@ -1,15 +1,29 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
 	"social-network/internal/models"
 	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
+	"time"
 )
 
+// normalizeEventDate validates an event date and rewrites it as RFC 3339 UTC
+func normalizeEventDate(event *models.GroupEvent) bool {
+	start, err := event.StartTime()
+	if err != nil {
+		return false
+	}
+	event.EventDate = start.Format(time.RFC3339)
+	return true
+}
+
 // CreateGroupEventHandler handles event creation inside a group
 func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 	userID := middlewares.GetUserIDFromSession(r)
@@ -28,10 +42,29 @@ func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "All fields are required", http.StatusBadRequest)
 		return
 	}
+	if !normalizeEventDate(&event) {
+		http.Error(w, "Invalid event date", http.StatusBadRequest)
+		return
+	}
+	if event.Capacity != nil && *event.Capacity < 1 {
+		http.Error(w, "Capacity must be at least 1", http.StatusBadRequest)
+		return
+	}
+	if event.Recurrence != nil {
+		if err := event.Recurrence.Normalize(); err != nil {
+			http.Error(w, "Invalid recurrence: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	event.CreatorID = userID
+	event.OccurrenceDate = ""
 
 	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, event.GroupID, models.GroupPermCreateEvents) || !requireGroupNotArchived(w, db, event.GroupID) {
+		return
+	}
+
 	repo := repositories.NewGroupEventRepository(db)
 
 	err := repo.CreateGroupEvent(&event)
@@ -41,6 +74,457 @@ func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyGroupOfNewEvent(db, &event)
+	if stored, err := repo.GetEventByID(event.ID); err == nil {
+		queueWebhookEvent(db, event.GroupID, models.WebhookEventGroupEventCreated, stored)
+	}
+
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Group event created successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Group event created successfully", "event_id": event.ID})
+}
+
+// eventRSVPLink is the deep link members follow to respond to an event: the event's details,
+// which clients show with the RSVP options
+func eventRSVPLink(eventID int) string {
+	return fmt.Sprintf("/api/groups/events/%d", eventID)
+}
+
+// notifyGroupOfNewEvent sends an event_created notification to every member except the creator
+func notifyGroupOfNewEvent(db *sql.DB, event *models.GroupEvent) {
+	groupRepo := repositories.NewGroupRepository(db)
+	group, err := groupRepo.GetGroupByID(event.GroupID)
+	if err != nil {
+		log.Println("❌ Failed to load group for event notification:", err)
+		return
+	}
+	members, err := groupRepo.GetGroupMembers(event.GroupID)
+	if err != nil {
+		log.Println("❌ Failed to load group members for event notification:", err)
+		return
+	}
+
+	message := fmt.Sprintf("New event in %s: \"%s\" on %s. RSVP: %s",
+		group.Name, event.Title, event.EventDate, eventRSVPLink(event.ID))
+	data := event.NotificationData()
+	data["rsvp_link"] = eventRSVPLink(event.ID)
+	for _, member := range members {
+		if member.UserID != event.CreatorID {
+			ws.Notify(models.Notification{
+				UserID:     member.UserID,
+				Type:       "event_created",
+				Message:    message,
+				ActorID:    event.CreatorID,
+				EntityType: models.NotificationEntityEvent,
+				EntityID:   event.ID,
+				Data:       data,
+			})
+		}
+	}
+}
+
+// GetGroupEventsHandler lists a group's events, optionally filtered with ?when=upcoming or ?when=past
+func GetGroupEventsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	when := r.URL.Query().Get("when")
+	if when != "" && when != "upcoming" && when != "past" {
+		http.Error(w, "Filter must be upcoming or past", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupVisible(w, db, userID, groupID) {
+		return
+	}
+
+	repo := repositories.NewGroupEventRepository(db)
+	events, err := repo.GetGroupEvents(groupID, when)
+	if err != nil {
+		log.Println("Error retrieving group events:", err)
+		http.Error(w, "Failed to retrieve events", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// loadVisibleEvent fetches an event and checks the user may see its group; it writes the error response itself
+func loadVisibleEvent(w http.ResponseWriter, db *sql.DB, userID, eventID int) (*models.GroupEvent, bool) {
+	event, err := repositories.NewGroupEventRepository(db).GetEventByID(eventID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve event", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !requireGroupVisible(w, db, userID, event.GroupID) {
+		return nil, false
+	}
+	return event, true
+}
+
+// resolveOccurrence checks the occurrence_date query parameter against an event and returns the
+// event as seen on that date. One-off events take no occurrence; recurring events need one of their
+// scheduled, non-skipped starts unless required is false, in which case the whole series is returned.
+// It writes the error response itself.
+func resolveOccurrence(w http.ResponseWriter, r *http.Request, db *sql.DB, event *models.GroupEvent, required bool) (*models.GroupEvent, bool) {
+	raw := r.URL.Query().Get("occurrence_date")
+	if event.Recurrence == nil {
+		if raw != "" {
+			http.Error(w, "This event doesn't repeat", http.StatusBadRequest)
+			return nil, false
+		}
+		return event, true
+	}
+	if raw == "" {
+		if required {
+			http.Error(w, "occurrence_date is required for recurring events", http.StatusBadRequest)
+			return nil, false
+		}
+		return event, true
+	}
+
+	start, err := models.ParseEventDate(raw)
+	if err != nil || !event.HasOccurrence(start) {
+		http.Error(w, "The event doesn't take place on that date", http.StatusNotFound)
+		return nil, false
+	}
+	overrides, err := repositories.NewGroupEventRepository(db).GetOccurrenceOverrides(event.ID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve event", http.StatusInternalServerError)
+		return nil, false
+	}
+	override, ok := overrides[models.OccurrenceKey(start)]
+	if !ok {
+		occurrence := event.AtOccurrence(start, nil)
+		return &occurrence, true
+	}
+	if override.Excluded {
+		http.Error(w, "This occurrence has been cancelled", http.StatusGone)
+		return nil, false
+	}
+	occurrence := event.AtOccurrence(start, &override)
+	return &occurrence, true
+}
+
+// GetGroupEventHandler returns an event, or one occurrence of a recurring event, with its
+// attendees grouped by RSVP status
+func GetGroupEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventID := pathID(r, "eventID")
+	if eventID == 0 {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	event, ok := loadVisibleEvent(w, db, userID, eventID)
+	if !ok {
+		return
+	}
+	event, ok = resolveOccurrence(w, r, db, event, false)
+	if !ok {
+		return
+	}
+
+	attendees, err := repositories.NewEventRSVPRepository(db).GetAttendees(eventID, event.OccurrenceDate)
+	if err != nil {
+		log.Println("Error retrieving attendees:", err)
+		http.Error(w, "Failed to retrieve attendees", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.GroupEventDetail{GroupEvent: *event, Attendees: attendees})
+}
+
+// canManageEvent reports whether the user created the event or may manage its group's events
+func canManageEvent(db *sql.DB, userID int, event *models.GroupEvent) (bool, error) {
+	if event.CreatorID == userID {
+		return true, nil
+	}
+	return repositories.NewGroupRepository(db).HasGroupPermission(userID, event.GroupID, models.GroupPermManageEvents)
+}
+
+// notifyEventRespondents tells everyone who RSVP'd to an event about a change, except the actor.
+// For one occurrence of a recurring event only its respondents are told; otherwise everyone is.
+func notifyEventRespondents(db *sql.DB, event *models.GroupEvent, actorID int, notifType, message string) {
+	userIDs, err := repositories.NewEventRSVPRepository(db).GetRespondentIDs(event.ID, event.OccurrenceDate)
+	if err != nil {
+		log.Println("❌ Failed to load event respondents:", err)
+		return
+	}
+	for _, userID := range userIDs {
+		if userID != actorID {
+			ws.Notify(models.Notification{
+				UserID:     userID,
+				Type:       notifType,
+				Message:    message,
+				ActorID:    actorID,
+				EntityType: models.NotificationEntityEvent,
+				EntityID:   event.ID,
+				Data:       event.NotificationData(),
+			})
+		}
+	}
+}
+
+// UpdateGroupEventHandler lets the event creator or a group admin edit an event. For recurring
+// events, ?occurrence_date= edits only that occurrence; without it the whole series changes.
+func UpdateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventID := pathID(r, "eventID")
+	if eventID == 0 {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Title       string                  `json:"title"`
+		Description string                  `json:"description"`
+		EventDate   string                  `json:"event_date"`
+		Capacity    *int                    `json:"capacity"` // 0 removes the limit
+		Recurrence  *models.EventRecurrence `json:"recurrence"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	event, ok := loadVisibleEvent(w, db, userID, eventID)
+	if !ok {
+		return
+	}
+
+	allowed, err := canManageEvent(db, userID, event)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Only the event creator or a group admin can edit this event", http.StatusForbidden)
+		return
+	}
+	if event.CancelledAt != nil {
+		http.Error(w, "Cancelled events can't be edited", http.StatusConflict)
+		return
+	}
+	if !requireGroupNotArchived(w, db, event.GroupID) {
+		return
+	}
+
+	if r.URL.Query().Get("occurrence_date") != "" {
+		if requestBody.Capacity != nil || requestBody.Recurrence != nil {
+			http.Error(w, "Capacity and recurrence can only be changed for the whole series", http.StatusBadRequest)
+			return
+		}
+		updateEventOccurrence(w, r, db, userID, event, requestBody.Title, requestBody.Description, requestBody.EventDate)
+		return
+	}
+
+	// Only overwrite the fields that were sent
+	previousDate := event.EventDate
+	if requestBody.Title != "" {
+		event.Title = requestBody.Title
+	}
+	if requestBody.Description != "" {
+		event.Description = requestBody.Description
+	}
+	if requestBody.EventDate != "" {
+		event.EventDate = requestBody.EventDate
+		if !normalizeEventDate(event) {
+			http.Error(w, "Invalid event date", http.StatusBadRequest)
+			return
+		}
+	}
+	if requestBody.Recurrence != nil {
+		if err := requestBody.Recurrence.Normalize(); err != nil {
+			http.Error(w, "Invalid recurrence: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		event.Recurrence = requestBody.Recurrence // Its exceptions replace the stored ones
+	}
+	if requestBody.Capacity != nil {
+		switch {
+		case *requestBody.Capacity < 0:
+			http.Error(w, "Capacity can't be negative", http.StatusBadRequest)
+			return
+		case *requestBody.Capacity == 0:
+			event.Capacity = nil
+		default:
+			event.Capacity = requestBody.Capacity
+		}
+	}
+
+	repo := repositories.NewGroupEventRepository(db)
+
+	// RSVPs and occurrence changes are keyed by scheduled start, so a new schedule must still
+	// contain every occurrence they were made for
+	if event.EventDate != previousDate || requestBody.Recurrence != nil {
+		keys, err := repo.GetOccurrenceKeys(event.ID, requestBody.Recurrence == nil)
+		if err != nil {
+			log.Println("❌ Error loading event occurrences:", err)
+			http.Error(w, "Failed to update event", http.StatusInternalServerError)
+			return
+		}
+		for _, key := range keys {
+			if !event.IsScheduledAt(key) {
+				http.Error(w, "This schedule change would detach existing RSVPs or occurrence changes; "+
+					"cancel the event and create a new one instead", http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	if err := repo.UpdateGroupEvent(event, requestBody.Recurrence != nil); err != nil {
+		log.Println("Error updating group event:", err)
+		http.Error(w, "Failed to update event", http.StatusInternalServerError)
+		return
+	}
+
+	// A raised or removed limit frees spots for waitlisted users
+	promoted, err := repositories.NewEventRSVPRepository(db).FillFromWaitlist(eventID)
+	if err != nil {
+		log.Println("❌ Failed to promote waitlisted users:", err)
+	}
+	notifyPromotedAttendees(event, promoted)
+
+	notifyEventRespondents(db, event, userID, "event_updated",
+		fmt.Sprintf("The event \"%s\" was updated. It now takes place on %s.", event.Title, event.EventDate))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Event updated successfully"})
+}
+
+// updateEventOccurrence changes a single occurrence of a recurring event; empty fields are left as they are
+func updateEventOccurrence(w http.ResponseWriter, r *http.Request, db *sql.DB, userID int, event *models.GroupEvent, title, description, eventDate string) {
+	occurrence, ok := resolveOccurrence(w, r, db, event, true)
+	if !ok {
+		return
+	}
+
+	override := models.EventOccurrenceOverride{OccurrenceDate: occurrence.OccurrenceDate}
+	if title != "" {
+		override.Title = &title
+		occurrence.Title = title
+	}
+	if description != "" {
+		override.Description = &description
+		occurrence.Description = description
+	}
+	if eventDate != "" {
+		occurrence.EventDate = eventDate
+		if !normalizeEventDate(occurrence) {
+			http.Error(w, "Invalid event date", http.StatusBadRequest)
+			return
+		}
+		override.EventDate = &occurrence.EventDate
+	}
+
+	repo := repositories.NewGroupEventRepository(db)
+	if err := repo.SaveOccurrenceOverride(event.ID, &override); err != nil {
+		log.Println("Error updating event occurrence:", err)
+		http.Error(w, "Failed to update event", http.StatusInternalServerError)
+		return
+	}
+
+	notifyEventRespondents(db, occurrence, userID, "event_updated",
+		fmt.Sprintf("The %s occurrence of \"%s\" was updated. It now takes place on %s.",
+			occurrence.OccurrenceDate, occurrence.Title, occurrence.EventDate))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Occurrence updated successfully"})
+}
+
+// CancelGroupEventHandler lets the event creator or a group admin cancel an event. For recurring
+// events, ?occurrence_date= cancels only that occurrence; without it the whole series is cancelled.
+func CancelGroupEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventID := pathID(r, "eventID")
+	if eventID == 0 {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	event, ok := loadVisibleEvent(w, db, userID, eventID)
+	if !ok {
+		return
+	}
+
+	allowed, err := canManageEvent(db, userID, event)
+	if err != nil {
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "Only the event creator or a group admin can cancel this event", http.StatusForbidden)
+		return
+	}
+	if event.CancelledAt != nil {
+		http.Error(w, "Event is already cancelled", http.StatusConflict)
+		return
+	}
+
+	repo := repositories.NewGroupEventRepository(db)
+
+	if r.URL.Query().Get("occurrence_date") != "" {
+		occurrence, ok := resolveOccurrence(w, r, db, event, true)
+		if !ok {
+			return
+		}
+		if err := repo.ExcludeOccurrence(eventID, occurrence.OccurrenceDate); err != nil {
+			log.Println("Error cancelling event occurrence:", err)
+			http.Error(w, "Failed to cancel event", http.StatusInternalServerError)
+			return
+		}
+
+		notifyEventRespondents(db, occurrence, userID, "event_cancelled",
+			fmt.Sprintf("The event \"%s\" on %s has been cancelled.", occurrence.Title, occurrence.EventDate))
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Occurrence cancelled"})
+		return
+	}
+
+	if err := repo.CancelGroupEvent(eventID); err != nil {
+		log.Println("Error cancelling group event:", err)
+		http.Error(w, "Failed to cancel event", http.StatusInternalServerError)
+		return
+	}
+
+	notifyEventRespondents(db, event, userID, "event_cancelled",
+		fmt.Sprintf("The event \"%s\" on %s has been cancelled.", event.Title, event.EventDate))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Event cancelled"})
 }
@@ -1,16 +1,75 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
 	"social-network/internal/models"
 	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
 	"strconv"
+
+	"github.com/gorilla/mux"
 )
 
+// pathID reads a numeric route variable such as {id}; it returns 0 if missing or invalid
+func pathID(r *http.Request, name string) int {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// requireGroupMember writes a 403 and returns false unless the user is an approved group member
+func requireGroupMember(w http.ResponseWriter, db *sql.DB, userID, groupID int) bool {
+	isMember, err := repositories.NewGroupRepository(db).IsGroupMember(userID, groupID)
+	if err != nil {
+		log.Println("❌ Error checking group membership:", err)
+		http.Error(w, "Failed to verify membership", http.StatusInternalServerError)
+		return false
+	}
+	if !isMember {
+		http.Error(w, "You must be a member of this group", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireGroupPermission writes a 403 and returns false unless the user's group role grants the permission
+func requireGroupPermission(w http.ResponseWriter, db *sql.DB, userID, groupID int, permission string) bool {
+	allowed, err := repositories.NewGroupRepository(db).HasGroupPermission(userID, groupID, permission)
+	if err != nil {
+		log.Println("❌ Error checking group permission:", err)
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "You don't have permission to do this in this group", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// loadGroup loads a group, writing a 404 or 500 and returning false if that fails
+func loadGroup(w http.ResponseWriter, repo *repositories.GroupRepository, groupID int) (*models.Group, bool) {
+	group, err := repo.GetGroupByID(groupID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Println("❌ Error loading group:", err)
+		http.Error(w, "Failed to fetch group", http.StatusInternalServerError)
+		return nil, false
+	}
+	return group, true
+}
+
 // CreateGroupHandler allows users to create a new group
 func CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
 	userID := middlewares.GetUserIDFromSession(r)
@@ -29,6 +88,10 @@ func CreateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Group name and description are required", http.StatusBadRequest)
 		return
 	}
+	if !validGroupPrivacy(&group) {
+		http.Error(w, "Privacy must be public or private", http.StatusBadRequest)
+		return
+	}
 
 	group.CreatorID = userID
 
@@ -63,14 +126,33 @@ func RequestToJoinGroupHandler(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 	repo := repositories.NewGroupRepository(db)
 
-	err = repo.RequestToJoinGroup(groupID, userID)
+	status, err := repo.RequestToJoinGroup(groupID, userID)
+	if err == repositories.ErrUserBanned {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == repositories.ErrJoinRequestPending || err == repositories.ErrAlreadyGroupMember {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to request to join group", http.StatusInternalServerError)
 		return
 	}
 
+	message := "Request sent"
+	if status == models.GroupRoleMember {
+		message = "Joined group successfully"
+		queueMemberJoinedWebhook(db, groupID, userID, "public")
+	} else {
+		notifyGroupOfJoinRequest(db, groupID, userID)
+	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Request sent"})
+	json.NewEncoder(w).Encode(map[string]string{"message": message, "status": status})
 }
 
 // ApproveMembershipHandler allows group admins to approve members
@@ -89,9 +171,12 @@ func ApproveMembershipHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := config.GetDB()
-	repo := repositories.NewGroupRepository(db)
 
-	err = repo.ApproveMembership(groupID, userID, adminID)
+	err = answerJoinRequest(db, groupID, userID, adminID, true)
+	if err == repositories.ErrNoPendingMembership {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Println("Error approving member:", err)
 		http.Error(w, err.Error(), http.StatusForbidden)
@@ -104,6 +189,12 @@ func ApproveMembershipHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetGroupMembersHandler retrieves all approved members of a group
 func GetGroupMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	groupID, err := strconv.Atoi(r.URL.Query().Get("group_id"))
 	if err != nil || groupID == 0 {
 		http.Error(w, "Invalid group ID", http.StatusBadRequest)
@@ -111,6 +202,10 @@ func GetGroupMembersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := config.GetDB()
+	if !requireGroupVisible(w, db, userID, groupID) {
+		return
+	}
+
 	repo := repositories.NewGroupRepository(db)
 
 	members, err := repo.GetGroupMembers(groupID)
@@ -140,9 +235,12 @@ func RejectMembershipHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := config.GetDB()
-	repo := repositories.NewGroupRepository(db)
 
-	err = repo.RejectMembership(groupID, userID, adminID)
+	err = answerJoinRequest(db, groupID, userID, adminID, false)
+	if err == repositories.ErrNoPendingMembership {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		log.Println("Error rejecting member:", err)
 		http.Error(w, err.Error(), http.StatusForbidden)
@@ -154,3 +252,260 @@ func RejectMembershipHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Attempting to join group:", userID, "Group:", groupID)
 
 }
+
+// notifyGroupOfJoinRequest sends an actionable group_join_request notification to every member
+// who can approve it
+func notifyGroupOfJoinRequest(db *sql.DB, groupID, requesterID int) {
+	groupRepo := repositories.NewGroupRepository(db)
+	group, err := groupRepo.GetGroupByID(groupID)
+	if err != nil {
+		log.Println("❌ Failed to load group for join request notification:", err)
+		return
+	}
+	members, err := groupRepo.GetGroupMembers(groupID)
+	if err != nil {
+		log.Println("❌ Failed to load group members for join request notification:", err)
+		return
+	}
+	nickname, err := repositories.NewUserRepository(db).GetNickname(requesterID)
+	if err != nil {
+		log.Println("❌ Failed to load requester for join request notification:", err)
+		return
+	}
+
+	for _, member := range members {
+		if models.GroupRoleHasPermission(member.Status, models.GroupPermApproveMembers) {
+			ws.Notify(models.Notification{
+				UserID:     member.UserID,
+				Type:       models.NotificationTypeGroupJoinRequest,
+				Message:    fmt.Sprintf("%s wants to join %s", nickname, group.Name),
+				ActorID:    requesterID,
+				EntityType: models.NotificationEntityGroup,
+				EntityID:   groupID,
+				State:      models.NotificationStatePending,
+			})
+		}
+	}
+}
+
+// answerJoinRequest approves or rejects a pending join request, resolves the notifications the
+// group's admins got about it and tells the requester the outcome
+func answerJoinRequest(db *sql.DB, groupID, requesterID, adminID int, accept bool) error {
+	groupRepo := repositories.NewGroupRepository(db)
+	var err error
+	if accept {
+		err = groupRepo.ApproveMembership(groupID, requesterID, adminID)
+	} else {
+		err = groupRepo.RejectMembership(groupID, requesterID, adminID)
+	}
+	if err != nil {
+		return err
+	}
+
+	state, notifType, outcome := models.NotificationStateAccepted, "group_join_accepted", "accepted"
+	if !accept {
+		state, notifType, outcome = models.NotificationStateDeclined, "group_join_declined", "declined"
+	}
+	resolveRequestNotifications(db, models.NotificationTypeGroupJoinRequest, models.NotificationEntityGroup, groupID, requesterID, state)
+	if accept {
+		queueMemberJoinedWebhook(db, groupID, requesterID, "approval")
+	}
+
+	groupName := fmt.Sprintf("group %d", groupID)
+	if group, err := groupRepo.GetGroupByID(groupID); err == nil {
+		groupName = group.Name
+	}
+	ws.Notify(models.Notification{
+		UserID:     requesterID,
+		Type:       notifType,
+		Message:    fmt.Sprintf("Your request to join %s was %s", groupName, outcome),
+		ActorID:    adminID,
+		EntityType: models.NotificationEntityGroup,
+		EntityID:   groupID,
+	})
+	return nil
+}
+
+// requireGroupVisible writes an error and returns false unless the user may see what a group
+// shows outsiders (public group, or approved member of a private one): its details, members,
+// events and RSVP counts, which people look at to decide whether to join or turn up. What
+// members say to each other (posts, comments, likes and chat) stays behind requireGroupMember
+// even in public groups, where joining is instant, so it's never readable by someone who hasn't
+// joined.
+func requireGroupVisible(w http.ResponseWriter, db *sql.DB, userID, groupID int) bool {
+	visible, err := repositories.NewGroupRepository(db).CanViewGroupContent(userID, groupID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		log.Println("❌ Error checking group visibility:", err)
+		http.Error(w, "Failed to verify membership", http.StatusInternalServerError)
+		return false
+	}
+	if !visible {
+		http.Error(w, "This group is private", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// validGroupPrivacy defaults an empty privacy to public and rejects unknown values
+func validGroupPrivacy(group *models.Group) bool {
+	if group.Privacy == "" {
+		group.Privacy = models.GroupPrivacyPublic
+	}
+	return group.Privacy == models.GroupPrivacyPublic || group.Privacy == models.GroupPrivacyPrivate
+}
+
+// requireGroupNotArchived writes a 403 and returns false if the group is archived (read-only)
+func requireGroupNotArchived(w http.ResponseWriter, db *sql.DB, groupID int) bool {
+	archived, err := repositories.NewGroupRepository(db).IsGroupArchived(groupID)
+	if err != nil {
+		log.Println("❌ Error checking group archive state:", err)
+		http.Error(w, "Failed to verify group state", http.StatusInternalServerError)
+		return false
+	}
+	if archived {
+		http.Error(w, "This group is archived and read-only", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// SetGroupMemberRoleHandler promotes or demotes a group member
+func SetGroupMemberRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserIDFromSession(r)
+	if actorID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	userID := pathID(r, "userID")
+	if groupID == 0 || userID == 0 {
+		http.Error(w, "Invalid group or user ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Role == "" {
+		http.Error(w, "Role is required", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+
+	err := repo.SetMemberRole(groupID, userID, actorID, requestBody.Role)
+	if err == repositories.ErrInvalidGroupRole {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == repositories.ErrCannotManageRoles || err == repositories.ErrRoleOutranked {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == repositories.ErrNotGroupMember {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error changing member role:", err)
+		http.Error(w, "Failed to change member role", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member role updated", "role": requestBody.Role})
+}
+
+// GetGroupHandler returns a group's details; private groups are still discoverable by name
+func GetGroupHandler(w http.ResponseWriter, r *http.Request) {
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+
+	group, err := repo.GetGroupByID(groupID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error getting group:", err)
+		http.Error(w, "Failed to fetch group", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
+
+// UpdateGroupHandler lets members whose role allows it edit the name, description and privacy
+// of a group that isn't archived
+func UpdateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody models.Group
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermEditGroup) {
+		return
+	}
+	if !requireGroupNotArchived(w, db, groupID) {
+		return
+	}
+
+	group, err := repo.GetGroupByID(groupID)
+	if err != nil {
+		http.Error(w, "Failed to fetch group", http.StatusInternalServerError)
+		return
+	}
+
+	// Only overwrite the fields that were sent
+	if requestBody.Name != "" {
+		group.Name = requestBody.Name
+	}
+	if requestBody.Description != "" {
+		group.Description = requestBody.Description
+	}
+	if requestBody.Privacy != "" {
+		group.Privacy = requestBody.Privacy
+	}
+	if !validGroupPrivacy(group) {
+		http.Error(w, "Privacy must be public or private", http.StatusBadRequest)
+		return
+	}
+
+	if err := repo.UpdateGroup(group); err != nil {
+		log.Println("Error updating group:", err)
+		http.Error(w, "Failed to update group", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(group)
+}
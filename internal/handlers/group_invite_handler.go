@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"social-network/internal/config"
+	"social-network/internal/middlewares"
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+)
+
+// CreateGroupInviteLinkHandler lets a group admin generate a shareable invite link
+func CreateGroupInviteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		ExpiresAt *time.Time `json:"expires_at"` // Optional, RFC 3339
+		MaxUses   *int       `json:"max_uses"`   // Optional
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+	if requestBody.ExpiresAt != nil && requestBody.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+	if requestBody.MaxUses != nil && *requestBody.MaxUses <= 0 {
+		http.Error(w, "Max uses must be positive", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageInvites) {
+		return
+	}
+
+	link := models.GroupInviteLink{
+		GroupID:   groupID,
+		CreatedBy: userID,
+		ExpiresAt: requestBody.ExpiresAt,
+		MaxUses:   requestBody.MaxUses,
+	}
+	repo := repositories.NewGroupInviteRepository(db)
+	if err := repo.CreateInviteLink(&link); err != nil {
+		http.Error(w, "Failed to create invite link", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// GetGroupInviteLinksHandler lists a group's invite links for its admins
+func GetGroupInviteLinksHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageInvites) {
+		return
+	}
+
+	repo := repositories.NewGroupInviteRepository(db)
+	links, err := repo.GetGroupInviteLinks(groupID)
+	if err != nil {
+		log.Println("❌ Error fetching invite links:", err)
+		http.Error(w, "Failed to fetch invite links", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(links)
+}
+
+// RevokeGroupInviteLinkHandler disables an invite link
+func RevokeGroupInviteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	linkID := pathID(r, "linkID")
+	if groupID == 0 || linkID == 0 {
+		http.Error(w, "Invalid group or link ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageInvites) {
+		return
+	}
+
+	repo := repositories.NewGroupInviteRepository(db)
+	err := repo.RevokeInviteLink(groupID, linkID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Invite link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error revoking invite link:", err)
+		http.Error(w, "Failed to revoke invite link", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invite link revoked"})
+}
+
+// JoinGroupByLinkHandler adds the caller to a group using an invite token
+func JoinGroupByLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Token == "" {
+		http.Error(w, "Invite token is required", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupInviteRepository(db)
+
+	groupID, err := repo.RedeemInviteLink(requestBody.Token, userID)
+	switch {
+	case err == repositories.ErrInviteLinkUnavailable:
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	case err == repositories.ErrUserBanned:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err == repositories.ErrAlreadyGroupMember:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Println("❌ Error joining group by link:", err)
+		http.Error(w, "Failed to join group", http.StatusInternalServerError)
+		return
+	}
+
+	queueMemberJoinedWebhook(db, groupID, userID, "invite_link")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Joined group successfully", "group_id": groupID})
+}
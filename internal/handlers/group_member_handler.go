@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
+	"social-network/internal/models"
 	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
 	"strconv"
+	"time"
 )
 
 // JoinGroupHandler allows a user to join a group
@@ -26,7 +32,7 @@ func JoinGroupHandler(w http.ResponseWriter, r *http.Request) {
 	db := config.GetDB()
 	repo := repositories.NewGroupMemberRepository(db)
 
-	err = repo.AddUserToGroup(groupID, userID, "member")
+	err = repo.AddUserToGroup(groupID, userID, models.GroupRoleMember)
 	if err != nil {
 		http.Error(w, "Failed to join group", http.StatusInternalServerError)
 		return
@@ -51,6 +57,16 @@ func LeaveGroupHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	db := config.GetDB()
+	role, err := repositories.NewGroupRepository(db).GetMemberRole(userID, groupID)
+	if err != nil {
+		http.Error(w, "Failed to leave group", http.StatusInternalServerError)
+		return
+	}
+	if role == models.GroupRoleOwner {
+		http.Error(w, "The group owner can't leave the group; transfer ownership first", http.StatusForbidden)
+		return
+	}
+
 	repo := repositories.NewGroupMemberRepository(db)
 
 	err = repo.RemoveUserFromGroup(groupID, userID)
@@ -62,3 +78,193 @@ func LeaveGroupHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Left group successfully"})
 }
+
+// isModerationPermissionError reports whether removing or banning a user failed because the
+// acting user isn't allowed to do it
+func isModerationPermissionError(err error) bool {
+	return err == repositories.ErrCannotModerateSelf || err == repositories.ErrCannotRemoveMembers ||
+		err == repositories.ErrMemberOutranked
+}
+
+// RemoveGroupMemberHandler lets moderators with the remove_members permission kick a member
+func RemoveGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserIDFromSession(r)
+	if actorID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	userID := pathID(r, "userID")
+	if groupID == 0 || userID == 0 {
+		http.Error(w, "Invalid group or user ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+	group, ok := loadGroup(w, repo, groupID)
+	if !ok {
+		return
+	}
+
+	err := repo.RemoveMember(groupID, userID, actorID)
+	if isModerationPermissionError(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == repositories.ErrNotGroupMember {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error removing member:", err)
+		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+
+	ws.GroupChatHub.EvictUser(groupID, userID, "You have been removed from this group")
+	ws.Notify(models.Notification{
+		UserID:     userID,
+		Type:       "group_removed",
+		Message:    fmt.Sprintf("You have been removed from %s", group.Name),
+		ActorID:    actorID,
+		EntityType: models.NotificationEntityGroup,
+		EntityID:   groupID,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member removed"})
+}
+
+// BanGroupMemberHandler removes a user from a group and blocks them from rejoining
+func BanGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserIDFromSession(r)
+	if actorID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		UserID    int        `json:"user_id"`
+		Reason    string     `json:"reason"`     // Optional
+		ExpiresAt *time.Time `json:"expires_at"` // Optional, RFC 3339; omit for a permanent ban
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.UserID == 0 {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+	if requestBody.ExpiresAt != nil && requestBody.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Ban expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+	group, ok := loadGroup(w, repo, groupID)
+	if !ok {
+		return
+	}
+
+	err := repo.BanMember(groupID, requestBody.UserID, actorID, requestBody.Reason, requestBody.ExpiresAt)
+	if isModerationPermissionError(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error banning member:", err)
+		http.Error(w, "Failed to ban user", http.StatusInternalServerError)
+		return
+	}
+
+	ws.GroupChatHub.EvictUser(groupID, requestBody.UserID, "You have been banned from this group")
+	ws.Notify(models.Notification{
+		UserID:     requestBody.UserID,
+		Type:       "group_banned",
+		Message:    fmt.Sprintf("You have been banned from %s", group.Name),
+		ActorID:    actorID,
+		EntityType: models.NotificationEntityGroup,
+		EntityID:   groupID,
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User banned"})
+}
+
+// UnbanGroupMemberHandler lifts a ban so the user may request to join again
+func UnbanGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserIDFromSession(r)
+	if actorID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	userID := pathID(r, "userID")
+	if groupID == 0 || userID == 0 {
+		http.Error(w, "Invalid group or user ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+
+	err := repo.UnbanMember(groupID, userID, actorID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User is not banned", http.StatusNotFound)
+		return
+	}
+	if err == repositories.ErrCannotManageBans {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error lifting ban:", err)
+		http.Error(w, "Failed to lift ban", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Ban lifted"})
+}
+
+// GetGroupBansHandler lists the active bans of a group for its admins
+func GetGroupBansHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermRemoveMembers) {
+		return
+	}
+
+	repo := repositories.NewGroupRepository(db)
+	bans, err := repo.GetGroupBans(groupID)
+	if err != nil {
+		log.Println("❌ Error fetching group bans:", err)
+		http.Error(w, "Failed to fetch bans", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bans)
+}
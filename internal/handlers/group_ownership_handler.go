@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"social-network/internal/config"
+	"social-network/internal/middlewares"
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
+)
+
+// requireGroupOwner writes a 403 and returns false unless the user owns the group
+func requireGroupOwner(w http.ResponseWriter, db *sql.DB, userID, groupID int) bool {
+	role, err := repositories.NewGroupRepository(db).GetMemberRole(userID, groupID)
+	if err != nil {
+		log.Println("❌ Error checking group owner:", err)
+		http.Error(w, "Failed to verify permissions", http.StatusInternalServerError)
+		return false
+	}
+	if role != models.GroupRoleOwner {
+		http.Error(w, "Only the group owner can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// TransferGroupOwnershipHandler lets the owner offer the group to one of its admins
+func TransferGroupOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.UserID == 0 {
+		http.Error(w, "Target user ID is required", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+
+	group, ok := loadGroup(w, repo, groupID)
+	if !ok {
+		return
+	}
+
+	transfer, err := repo.RequestOwnershipTransfer(groupID, userID, requestBody.UserID)
+	if err == repositories.ErrNotGroupOwner {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == repositories.ErrTransferTargetNotAdmin {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error requesting ownership transfer:", err)
+		http.Error(w, "Failed to request ownership transfer", http.StatusInternalServerError)
+		return
+	}
+
+	ws.Notify(models.Notification{
+		UserID:     requestBody.UserID,
+		Type:       "group_ownership_transfer",
+		Message:    fmt.Sprintf("You have been offered ownership of %s", group.Name),
+		ActorID:    userID,
+		EntityType: models.NotificationEntityGroup,
+		EntityID:   groupID,
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// AcceptGroupOwnershipHandler lets the invited admin accept ownership
+func AcceptGroupOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	respondToOwnershipTransfer(w, r, true)
+}
+
+// DeclineGroupOwnershipHandler lets the invited admin decline ownership
+func DeclineGroupOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	respondToOwnershipTransfer(w, r, false)
+}
+
+func respondToOwnershipTransfer(w http.ResponseWriter, r *http.Request, accept bool) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupRepository(db)
+
+	group, ok := loadGroup(w, repo, groupID)
+	if !ok {
+		return
+	}
+
+	transfer, err := repo.GetPendingOwnershipTransfer(groupID)
+	if err != nil {
+		http.Error(w, "Failed to load ownership transfer", http.StatusInternalServerError)
+		return
+	}
+
+	if accept {
+		err = repo.AcceptOwnershipTransfer(groupID, userID)
+	} else {
+		err = repo.DeclineOwnershipTransfer(groupID, userID)
+	}
+	if err == repositories.ErrNoPendingTransfer {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err == repositories.ErrTransferOwnerChanged || err == repositories.ErrTransferAdminChanged {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error responding to ownership transfer:", err)
+		http.Error(w, "Failed to respond to ownership transfer", http.StatusInternalServerError)
+		return
+	}
+
+	outcome := "declined"
+	if accept {
+		outcome = "accepted"
+	}
+	ws.Notify(models.Notification{
+		UserID:     transfer.FromUserID,
+		Type:       "group_ownership_transfer",
+		Message:    fmt.Sprintf("Your ownership transfer for %s was %s", group.Name, outcome),
+		ActorID:    userID,
+		EntityType: models.NotificationEntityGroup,
+		EntityID:   groupID,
+		Data:       map[string]interface{}{"outcome": outcome},
+	})
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Ownership transfer " + outcome})
+}
+
+// ArchiveGroupHandler makes a group read-only; history stays visible
+func ArchiveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	setGroupArchived(w, r, true)
+}
+
+// UnarchiveGroupHandler restores an archived group
+func UnarchiveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	setGroupArchived(w, r, false)
+}
+
+func setGroupArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupOwner(w, db, userID, groupID) {
+		return
+	}
+
+	repo := repositories.NewGroupRepository(db)
+	if err := repo.SetGroupArchived(groupID, archived); err != nil {
+		log.Println("❌ Error updating group archive state:", err)
+		http.Error(w, "Failed to update group", http.StatusInternalServerError)
+		return
+	}
+
+	message := "Group restored"
+	if archived {
+		message = "Group archived"
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// DeleteGroupHandler permanently deletes a group and everything in it
+func DeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupOwner(w, db, userID, groupID) {
+		return
+	}
+
+	repo := repositories.NewGroupRepository(db)
+	if err := repo.DeleteGroup(groupID); err != nil {
+		http.Error(w, "Failed to delete group", http.StatusInternalServerError)
+		return
+	}
+
+	// ✅ Kick everyone out of the group's live chat
+	ws.GroupChatHub.DisconnectGroup(groupID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Group deleted successfully"})
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
 	"social-network/internal/models"
 	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
 )
 
 // CreateGroupPostHandler handles posting inside a group
@@ -32,6 +35,10 @@ func CreateGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 	post.UserID = userID
 
 	db := config.GetDB()
+	if !requireGroupMember(w, db, userID, post.GroupID) || !requireGroupNotArchived(w, db, post.GroupID) {
+		return
+	}
+
 	repo := repositories.NewGroupPostRepository(db)
 
 	err := repo.CreateGroupPost(&post)
@@ -41,6 +48,227 @@ func CreateGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyGroupOfNewPost(db, &post)
+	if stored, err := repo.GetGroupPostByID(post.ID); err == nil {
+		queueWebhookEvent(db, post.GroupID, models.WebhookEventGroupPostCreated, stored)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Group post created successfully"})
 }
+
+// notifyGroupOfNewPost sends a group_post notification to every member except the author
+func notifyGroupOfNewPost(db *sql.DB, post *models.GroupPost) {
+	groupRepo := repositories.NewGroupRepository(db)
+	group, err := groupRepo.GetGroupByID(post.GroupID)
+	if err != nil {
+		log.Println("❌ Failed to load group for post notification:", err)
+		return
+	}
+	members, err := groupRepo.GetGroupMembers(post.GroupID)
+	if err != nil {
+		log.Println("❌ Failed to load group members for post notification:", err)
+		return
+	}
+	nickname, err := repositories.NewUserRepository(db).GetNickname(post.UserID)
+	if err != nil {
+		log.Println("❌ Failed to load author for post notification:", err)
+		return
+	}
+
+	message := fmt.Sprintf("%s posted in %s: %s", nickname, group.Name, excerpt(post.Content))
+	for _, member := range members {
+		if member.UserID != post.UserID {
+			ws.Notify(models.Notification{
+				UserID:     member.UserID,
+				Type:       models.NotificationTypeGroupPost,
+				Message:    message,
+				ActorID:    post.UserID,
+				EntityType: models.NotificationEntityGroupPost,
+				EntityID:   post.ID,
+				Data:       map[string]interface{}{"group_id": post.GroupID},
+			})
+		}
+	}
+}
+
+// groupIDForContent returns the group a comment or group post belongs to, or 0 for regular posts
+func groupIDForContent(db *sql.DB, commentID, groupPostID int) (int, error) {
+	if groupPostID == 0 && commentID != 0 {
+		comment, err := repositories.NewCommentRepository(db).GetCommentByID(commentID)
+		if err != nil {
+			return 0, err
+		}
+		groupPostID = comment.GroupPostID
+	}
+	if groupPostID == 0 {
+		return 0, nil
+	}
+
+	post, err := repositories.NewGroupPostRepository(db).GetGroupPostByID(groupPostID)
+	if err != nil {
+		return 0, err
+	}
+	return post.GroupID, nil
+}
+
+// GetGroupPostsHandler lists a group's posts for its members
+func GetGroupPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	// Posts are members-only even in public groups; see requireGroupVisible
+	db := config.GetDB()
+	if !requireGroupMember(w, db, userID, groupID) {
+		return
+	}
+
+	repo := repositories.NewGroupPostRepository(db)
+	posts, err := repo.GetGroupPosts(groupID)
+	if err != nil {
+		log.Println("Error retrieving group posts:", err)
+		http.Error(w, "Failed to retrieve posts", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(posts)
+}
+
+// GetGroupPostHandler retrieves a single group post for members of its group
+func GetGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID := pathID(r, "postID")
+	if postID == 0 {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupPostRepository(db)
+
+	post, err := repo.GetGroupPostByID(postID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error retrieving group post:", err)
+		http.Error(w, "Failed to retrieve post", http.StatusInternalServerError)
+		return
+	}
+	if !requireGroupMember(w, db, userID, post.GroupID) { // Members-only, like the post list
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(post)
+}
+
+// EditGroupPostHandler lets authors edit their own group posts
+func EditGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID := pathID(r, "postID")
+	if postID == 0 {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody struct {
+		Content string  `json:"content"`
+		Image   *string `json:"image"` // Nullable field
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Content == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupPostRepository(db)
+
+	post, err := repo.GetGroupPostByID(postID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to edit post", http.StatusInternalServerError)
+		return
+	}
+	if post.UserID != userID {
+		http.Error(w, "You can only edit your own posts", http.StatusForbidden)
+		return
+	}
+	if !requireGroupNotArchived(w, db, post.GroupID) {
+		return
+	}
+
+	err = repo.EditGroupPost(postID, userID, requestBody.Content, requestBody.Image)
+	if err != nil {
+		log.Println("Error editing group post:", err)
+		http.Error(w, "Failed to edit post", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Group post updated successfully"})
+}
+
+// DeleteGroupPostHandler lets authors, or members with the delete_posts permission, delete a group post
+func DeleteGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID := pathID(r, "postID")
+	if postID == 0 {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewGroupPostRepository(db)
+
+	post, err := repo.GetGroupPostByID(postID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+		return
+	}
+	if post.UserID != userID && !requireGroupPermission(w, db, userID, post.GroupID, models.GroupPermDeletePosts) {
+		return
+	}
+
+	if err := repo.DeleteGroupPost(postID); err != nil {
+		log.Println("Error deleting group post:", err)
+		http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Group post deleted successfully"})
+}
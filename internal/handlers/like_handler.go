@@ -1,16 +1,20 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
+	"social-network/internal/models"
 	"social-network/internal/repositories"
+	ws "social-network/internal/websocket"
 	"strconv"
+	"strings"
 )
 
-// ToggleLikeHandler handles liking/unliking posts or comments
+// ToggleLikeHandler handles liking/unliking posts, comments or group posts
 func ToggleLikeHandler(w http.ResponseWriter, r *http.Request) {
 	userID := middlewares.GetUserIDFromSession(r)
 	if userID == 0 {
@@ -18,19 +22,25 @@ func ToggleLikeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	postID, _ := strconv.Atoi(r.URL.Query().Get("post_id"))
-	commentID, _ := strconv.Atoi(r.URL.Query().Get("comment_id"))
-
-	// Validate request
-	if postID == 0 && commentID == 0 {
-		http.Error(w, "Invalid request: Must provide post_id or comment_id", http.StatusBadRequest)
+	postID, commentID, groupPostID, ok := likeTarget(w, r)
+	if !ok {
 		return
 	}
 
 	db := config.GetDB()
+
+	// ✅ Only people who can see content can like it, and archived groups are read-only
+	groupID, ok := requireLikeTargetVisible(w, db, userID, postID, commentID, groupPostID)
+	if !ok {
+		return
+	}
+	if groupID != 0 && !requireGroupNotArchived(w, db, groupID) {
+		return
+	}
+
 	repo := repositories.NewLikeRepository(db)
 
-	liked, err := repo.ToggleLike(userID, postID, commentID)
+	liked, err := repo.ToggleLike(userID, postID, commentID, groupPostID)
 	if err != nil {
 		log.Println("Error toggling like:", err)
 		http.Error(w, "Failed to like/unlike", http.StatusInternalServerError)
@@ -41,27 +51,29 @@ func ToggleLikeHandler(w http.ResponseWriter, r *http.Request) {
 	if !liked {
 		status = "unliked"
 	}
+	notifyLike(db, userID, postID, commentID, groupPostID, liked)
 
 	// Send response
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": status})
 }
 
-// GetLikeCountHandler retrieves the like count for a post or comment
+// GetLikeCountHandler retrieves the like count for a post, comment or group post
 func GetLikeCountHandler(w http.ResponseWriter, r *http.Request) {
-	postID, _ := strconv.Atoi(r.URL.Query().Get("post_id"))
-	commentID, _ := strconv.Atoi(r.URL.Query().Get("comment_id"))
-
-	// Validate request
-	if postID == 0 && commentID == 0 {
-		http.Error(w, "Invalid request: Must provide post_id or comment_id", http.StatusBadRequest)
+	userID := middlewares.GetUserIDFromSession(r)
+	postID, commentID, groupPostID, ok := likeTarget(w, r)
+	if !ok {
 		return
 	}
 
 	db := config.GetDB()
+	if _, ok := requireLikeTargetVisible(w, db, userID, postID, commentID, groupPostID); !ok {
+		return
+	}
+
 	repo := repositories.NewLikeRepository(db)
 
-	likeCount, err := repo.GetLikeCount(postID, commentID)
+	likeCount, err := repo.GetLikeCount(postID, commentID, groupPostID)
 	if err != nil {
 		log.Println("Error retrieving like count:", err)
 		http.Error(w, "Failed to get like count", http.StatusInternalServerError)
@@ -70,3 +82,115 @@ func GetLikeCountHandler(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(map[string]int{"like_count": likeCount})
 }
+
+// likeTarget reads which post, comment or group post a like request is about. Exactly one of
+// post_id, comment_id and group_post_id must be given.
+func likeTarget(w http.ResponseWriter, r *http.Request) (postID, commentID, groupPostID int, ok bool) {
+	postID, _ = strconv.Atoi(r.URL.Query().Get("post_id"))
+	commentID, _ = strconv.Atoi(r.URL.Query().Get("comment_id"))
+	groupPostID, _ = strconv.Atoi(r.URL.Query().Get("group_post_id"))
+
+	given := 0
+	for _, id := range []int{postID, commentID, groupPostID} {
+		if id != 0 {
+			given++
+		}
+	}
+	if given != 1 {
+		http.Error(w, "Invalid request: Must provide exactly one of post_id, comment_id or group_post_id", http.StatusBadRequest)
+		return 0, 0, 0, false
+	}
+	return postID, commentID, groupPostID, true
+}
+
+// requireLikeTargetVisible checks that the user can see the liked content, applying the post's
+// rules to a comment, and returns the group it belongs to (0 outside groups)
+func requireLikeTargetVisible(w http.ResponseWriter, db *sql.DB, userID, postID, commentID, groupPostID int) (int, bool) {
+	if commentID != 0 {
+		comment, err := repositories.NewCommentRepository(db).GetCommentByID(commentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Content not found", http.StatusNotFound)
+			return 0, false
+		}
+		if err != nil {
+			log.Println("❌ Error loading liked comment:", err)
+			http.Error(w, "Failed to verify access to the content", http.StatusInternalServerError)
+			return 0, false
+		}
+		postID, groupPostID = comment.PostID, comment.GroupPostID
+	}
+	if !requireCommentsVisible(w, db, userID, postID, groupPostID) {
+		return 0, false
+	}
+	if groupPostID == 0 {
+		return 0, true
+	}
+
+	groupID, err := groupIDForContent(db, 0, groupPostID)
+	if err != nil {
+		http.Error(w, "Content not found", http.StatusNotFound)
+		return 0, false
+	}
+	return groupID, true
+}
+
+// likedContent returns who wrote a post, comment or group post and how notifications refer to it
+func likedContent(db *sql.DB, postID, commentID, groupPostID int) (ownerID int, entityType string, entityID int, err error) {
+	switch {
+	case commentID != 0:
+		comment, err := repositories.NewCommentRepository(db).GetCommentByID(commentID)
+		if err != nil {
+			return 0, "", 0, err
+		}
+		return comment.UserID, models.NotificationEntityComment, commentID, nil
+	case groupPostID != 0:
+		post, err := repositories.NewGroupPostRepository(db).GetGroupPostByID(groupPostID)
+		if err != nil {
+			return 0, "", 0, err
+		}
+		return post.UserID, models.NotificationEntityGroupPost, groupPostID, nil
+	default:
+		ownerID, err := repositories.NewPostRepository(db).GetPostOwner(postID)
+		return ownerID, models.NotificationEntityPost, postID, err
+	}
+}
+
+// notifyLike tells the author of liked content about the like, or withdraws that notification
+// when the like is taken back. Liking your own content notifies nobody.
+func notifyLike(db *sql.DB, likerID, postID, commentID, groupPostID int, liked bool) {
+	ownerID, entityType, entityID, err := likedContent(db, postID, commentID, groupPostID)
+	if err != nil {
+		log.Println("❌ Failed to load liked content for notification:", err)
+		return
+	}
+	if ownerID == likerID {
+		return
+	}
+
+	if !liked {
+		updated, removed, err := repositories.NewNotificationRepository(db).
+			WithdrawActor(models.NotificationTypeLike, entityType, entityID, likerID)
+		if err != nil {
+			log.Println("❌ Failed to withdraw like notification:", err)
+			return
+		}
+		ws.PushChanges(updated, removed)
+		return
+	}
+
+	nickname, err := repositories.NewUserRepository(db).GetNickname(likerID)
+	if err != nil {
+		log.Println("❌ Failed to load liker for notification:", err)
+		return
+	}
+	action := "liked your " + strings.ReplaceAll(entityType, "_", " ")
+	ws.Notify(models.Notification{
+		UserID:     ownerID,
+		Type:       models.NotificationTypeLike,
+		Message:    models.AggregatedMessage(nickname, 1, action),
+		ActorID:    likerID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Data:       map[string]interface{}{"action": action},
+	})
+}
@@ -1,36 +1,49 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
 	"social-network/internal/config"
 	"social-network/internal/middlewares"
+	"social-network/internal/models"
 	"social-network/internal/repositories"
 	ws "social-network/internal/websocket" // alias for our internal websocket package
 
+	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
-// WebSocketNotificationHandler handles real-time notifications via WebSocket.
+// notificationUpgrader upgrades notification socket requests; like the chat sockets, it accepts
+// any origin because the frontend is served separately
+var notificationUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// WebSocketNotificationHandler handles real-time notifications via WebSocket for the session's
+// user. The socket receives notifications and unread counts, starting with the current count;
+// notifications sent while the user is offline are stored and listed by GetNotificationsHandler.
 func WebSocketNotificationHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract user_id from query parameters.
-	userIDStr := r.URL.Query().Get("user_id")
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil || userID == 0 {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Upgrade HTTP connection to WebSocket.
-	conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+	conn, err := notificationUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("❌ Failed to upgrade WebSocket:", err)
-		http.Error(w, "WebSocket upgrade failed", http.StatusInternalServerError)
-		return
+		return // Upgrade has already written an error response
 	}
 
 	// Set read deadline and pong handler to keep connection alive.
@@ -41,36 +54,46 @@ func WebSocketNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// Register the client connection for notifications.
-	ws.NotificationManager.RegisterClient(userID, conn)
-	log.Printf("✅ WebSocket connected for User %d", userID)
+	client := ws.NotificationManager.RegisterClient(userID, conn)
+	defer ws.NotificationManager.RemoveClient(userID, client)
+	ws.PushUnreadCount(userID)
 
-	// Start a ping ticker to send ping messages every 30 seconds.
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	// Run ping in a separate goroutine.
+	// Ping every 30 seconds until the connection closes.
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
 		for {
-			<-ticker.C
-			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Printf("❌ Failed to send ping to User %d: %v", userID, err)
+			select {
+			case <-done:
 				return
+			case <-ticker.C:
+				if err := client.Ping(); err != nil {
+					log.Printf("❌ Failed to send ping to User %d: %v", userID, err)
+					return
+				}
 			}
 		}
 	}()
 
-	// Keep the connection alive by reading messages.
+	// Keep the connection alive by reading messages; clients don't send any.
 	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("❌ WebSocket read error for User %d: %v", userID, err)
-			ws.NotificationManager.RemoveClient(userID)
-			break
+		if _, _, err := conn.ReadMessage(); err != nil {
+			log.Printf("⚠️ WebSocket closed for User %d: %v", userID, err)
+			return
 		}
-		// You can handle incoming messages here if needed.
 	}
-} // GetNotificationsHandler fetches all notifications for the authenticated user.
+}
+
+// Page sizes for the notification list
+const (
+	defaultNotificationPageSize = 20
+	maxNotificationPageSize     = 100
+)
+
+// GetNotificationsHandler fetches a page of notifications for the authenticated user, newest first.
+// Query parameters: limit, before_id (cursor from the previous page) and unread=true.
 func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := middlewares.GetUserIDFromSession(r)
 	if userID == 0 {
@@ -78,45 +101,194 @@ func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := config.GetDB()
-	repo := repositories.NewNotificationRepository(db)
+	query := r.URL.Query()
+	limit := defaultNotificationPageSize
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if parsed > maxNotificationPageSize {
+			parsed = maxNotificationPageSize
+		}
+		limit = parsed
+	}
+	beforeID := 0
+	if value := query.Get("before_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid before_id", http.StatusBadRequest)
+			return
+		}
+		beforeID = parsed
+	}
+	unreadOnly := query.Get("unread") == "true"
 
-	notifications, err := repo.GetNotifications(userID)
+	repo := repositories.NewNotificationRepository(config.GetDB())
+	notifications, err := repo.GetNotificationsPage(userID, unreadOnly, beforeID, limit)
 	if err != nil {
 		log.Println("❌ Error fetching notifications:", err)
 		http.Error(w, "Failed to retrieve notifications", http.StatusInternalServerError)
 		return
 	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// notificationStreamReplayLimit caps how many missed notifications are replayed on reconnect
+const notificationStreamReplayLimit = 100
+
+// notificationStreamKeepAlive is how often an idle stream sends a comment so proxies keep it open
+const notificationStreamKeepAlive = 25 * time.Second
+
+// NotificationStreamHandler streams notifications as Server-Sent Events, for clients that can't
+// use the notification WebSocket. Events carry the same JSON as the socket: "notification" events
+// have the notification ID as their event ID, "notification_removed" events name a deleted
+// notification, and "unread_count" events follow changes. A client reconnecting with
+// Last-Event-ID (or ?last_event_id=) first receives the notifications it missed.
+func NotificationStreamHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	lastID, _ := strconv.Atoi(lastEventID)
+
+	// Subscribe before replaying, so nothing sent in between is lost. The client may see a
+	// notification twice; it should replace notifications by ID, as it does for aggregated ones.
+	stream := ws.NotificationManager.Subscribe(userID)
+	defer ws.NotificationManager.Unsubscribe(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Tell nginx not to buffer the stream
+	w.WriteHeader(http.StatusOK)
+
+	repo := repositories.NewNotificationRepository(config.GetDB())
+	if lastID > 0 {
+		missed, err := repo.GetNotificationsAfter(userID, lastID, notificationStreamReplayLimit)
+		if err != nil {
+			log.Println("❌ Error replaying notifications:", err)
+		}
+		for _, notification := range missed {
+			if !writeStreamEvent(w, notification) {
+				return
+			}
+		}
+	}
+	if count, err := repo.CountUnread(userID); err == nil {
+		if !writeStreamEvent(w, ws.UnreadCountMessage{Type: "unread_count", UnreadCount: count}) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(notificationStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-stream.Events:
+			if !open {
+				return
+			}
+			if !writeSSE(w, event) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStreamEvent writes a notification or unread count as a Server-Sent Event
+func writeStreamEvent(w http.ResponseWriter, message interface{}) bool {
+	event, err := ws.NewStreamEvent(message)
+	if err != nil {
+		log.Println("❌ Error encoding stream event:", err)
+		return true
+	}
+	return writeSSE(w, event)
+}
+
+// writeSSE writes one event in the text/event-stream format, reporting whether the write succeeded
+func writeSSE(w http.ResponseWriter, event ws.StreamEvent) bool {
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return false
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, event.Data)
+	return err == nil
+}
+
+// GetUnreadCountHandler returns how many unread notifications the authenticated user has
+func GetUnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-	// Optionally, mark them as read immediately (or do this in a separate endpoint)
-	_, err = db.Exec("UPDATE notifications SET is_read = 1 WHERE user_id = ?", userID)
+	count, err := repositories.NewNotificationRepository(config.GetDB()).CountUnread(userID)
 	if err != nil {
-		log.Println("❌ Error marking notifications as read:", err)
+		log.Println("❌ Error counting unread notifications:", err)
+		http.Error(w, "Failed to count notifications", http.StatusInternalServerError)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(notifications)
+	json.NewEncoder(w).Encode(map[string]int{"unread_count": count})
 }
 
 // SendNotificationHandler allows an API request to create (and send) a notification.
 func SendNotificationHandler(w http.ResponseWriter, r *http.Request) {
 	// Decode JSON request body
 	var requestBody struct {
-		UserID  int    `json:"user_id"`
-		Type    string `json:"type"`
-		Message string `json:"message"`
+		UserID     int                    `json:"user_id"`
+		Type       string                 `json:"type"`
+		Message    string                 `json:"message"`
+		EntityType string                 `json:"entity_type"`
+		EntityID   int                    `json:"entity_id"`
+		Data       map[string]interface{} `json:"data"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
 
-	// Get database connection and initialize repository
-	db := config.GetDB()
-	repo := repositories.NewNotificationRepository(db)
-
-	// Save the notification in the database
-	err := repo.CreateNotification(requestBody.UserID, requestBody.Type, requestBody.Message)
+	// Deliver the notification according to the recipient's preferences
+	notification := models.Notification{
+		UserID:     requestBody.UserID,
+		Type:       requestBody.Type,
+		Message:    requestBody.Message,
+		ActorID:    middlewares.GetUserIDFromSession(r),
+		EntityType: requestBody.EntityType,
+		EntityID:   requestBody.EntityID,
+		Data:       requestBody.Data,
+	}
+	err := ws.Deliver(&notification)
 	if err != nil {
 		log.Println("❌ Failed to save notification:", err)
 		http.Error(w, "Failed to send notification", http.StatusInternalServerError)
@@ -125,14 +297,12 @@ func SendNotificationHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("📩 Notification sent to User %d: %s", requestBody.UserID, requestBody.Message)
 
-	// Also, if the user is online, send it via WebSocket
-	ws.SendNotification(requestBody.UserID, requestBody.Type, requestBody.Message)
-
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Notification sent successfully"})
 }
 
-// MarkNotificationsAsReadHandler marks all notifications as read for the authenticated user.
+// MarkNotificationsAsReadHandler marks the authenticated user's notifications as read.
+// The optional body {"ids": [...]} limits it to those notifications; without it, all are marked.
 func MarkNotificationsAsReadHandler(w http.ResponseWriter, r *http.Request) {
 	userID := middlewares.GetUserIDFromSession(r)
 	if userID == 0 {
@@ -140,15 +310,503 @@ func MarkNotificationsAsReadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var requestBody struct {
+		IDs []int `json:"ids"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+	}
+
 	db := config.GetDB()
 	repo := repositories.NewNotificationRepository(db)
 
-	err := repo.MarkNotificationsAsRead(userID)
+	message := "All notifications marked as read"
+	var err error
+	if requestBody.IDs == nil {
+		err = repo.MarkNotificationsAsRead(userID)
+	} else {
+		var changed int
+		changed, err = repo.MarkNotificationsRead(userID, requestBody.IDs)
+		message = fmt.Sprintf("%d notifications marked as read", changed)
+	}
 	if err != nil {
+		log.Println("❌ Error marking notifications as read:", err)
 		http.Error(w, "Failed to mark notifications as read", http.StatusInternalServerError)
 		return
 	}
+	ws.PushUnreadCount(userID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// MarkNotificationReadHandler marks a single notification as read
+func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID := pathID(r, "id")
+	if notificationID == 0 {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	repo := repositories.NewNotificationRepository(config.GetDB())
+	changed, err := repo.MarkNotificationsRead(userID, []int{notificationID})
+	if err == nil && changed == 0 {
+		// Nothing changed: the notification was already read, or isn't the user's
+		_, err = repo.GetNotification(userID, notificationID)
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error marking notification as read:", err)
+		http.Error(w, "Failed to mark notification as read", http.StatusInternalServerError)
+		return
+	}
+	ws.PushUnreadCount(userID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification marked as read"})
+}
+
+// DeleteNotificationHandler dismisses one of the authenticated user's notifications
+func DeleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notificationID := pathID(r, "id")
+
+	repo := repositories.NewNotificationRepository(config.GetDB())
+	err := repo.DeleteNotification(userID, notificationID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error deleting notification:", err)
+		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
+		return
+	}
+	ws.PushUnreadCount(userID)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification dismissed"})
+}
+
+// GetNotificationPreferencesHandler returns the authenticated user's channel choices for every
+// notification type
+func GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := repositories.NewNotificationSettingsRepository(config.GetDB()).GetPreferences(userID)
+	if err != nil {
+		log.Println("❌ Error fetching notification preferences:", err)
+		http.Error(w, "Failed to retrieve notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// UpdateNotificationPreferencesHandler changes channels for one or more notification types.
+// Body: [{"type": "like", "push": false, "email_digest": true}, ...]; omitted channels keep
+// their current setting.
+func UpdateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var changes []struct {
+		Type        string `json:"type"`
+		InApp       *bool  `json:"in_app"`
+		Push        *bool  `json:"push"`
+		EmailDigest *bool  `json:"email_digest"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	for _, change := range changes {
+		if !models.IsNotificationType(change.Type) {
+			http.Error(w, fmt.Sprintf("Unknown notification type %q", change.Type), http.StatusBadRequest)
+			return
+		}
+	}
+
+	repo := repositories.NewNotificationSettingsRepository(config.GetDB())
+	for _, change := range changes {
+		pref, err := repo.GetPreference(userID, change.Type)
+		if err != nil {
+			log.Println("❌ Error fetching notification preference:", err)
+			http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+			return
+		}
+		if change.InApp != nil {
+			pref.InApp = *change.InApp
+		}
+		if change.Push != nil {
+			pref.Push = *change.Push
+		}
+		if change.EmailDigest != nil {
+			pref.EmailDigest = *change.EmailDigest
+		}
+		if err := repo.SetPreference(userID, pref); err != nil {
+			log.Println("❌ Error updating notification preference:", err)
+			http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	preferences, err := repo.GetPreferences(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// GetNotificationMutesHandler lists the groups, events and group chats the user muted
+func GetNotificationMutesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mutes, err := repositories.NewNotificationSettingsRepository(config.GetDB()).GetMutes(userID)
+	if err != nil {
+		log.Println("❌ Error fetching notification mutes:", err)
+		http.Error(w, "Failed to retrieve muted items", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mutes)
+}
+
+// MuteNotificationsHandler mutes a group, event or group chat.
+// Body: {"entity_type": "group" | "event" | "group_chat", "entity_id": 1}
+func MuteNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var mute models.NotificationMute
+	if err := json.NewDecoder(r.Body).Decode(&mute); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !models.IsMutableEntity(mute.EntityType) || mute.EntityID == 0 {
+		http.Error(w, "Entity type must be group, event or group_chat, with an entity ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	var exists bool
+	var err error
+	if mute.EntityType == models.NotificationEntityEvent {
+		_, err = repositories.NewGroupEventRepository(db).GetEventByID(mute.EntityID)
+		exists = err == nil
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+	} else {
+		exists, err = repositories.NewGroupRepository(db).GroupIDExists(mute.EntityID)
+	}
+	if err != nil {
+		log.Println("❌ Error checking muted entity:", err)
+		http.Error(w, "Failed to mute notifications", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Nothing to mute with that ID", http.StatusNotFound)
+		return
+	}
+
+	if err := repositories.NewNotificationSettingsRepository(db).Mute(userID, mute.EntityType, mute.EntityID); err != nil {
+		log.Println("❌ Error muting notifications:", err)
+		http.Error(w, "Failed to mute notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Muted"})
+}
+
+// UnmuteNotificationsHandler removes a mute set with MuteNotificationsHandler
+func UnmuteNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entityType := mux.Vars(r)["entityType"]
+	entityID := pathID(r, "id")
+	err := repositories.NewNotificationSettingsRepository(config.GetDB()).Unmute(userID, entityType, entityID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not muted", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error unmuting notifications:", err)
+		http.Error(w, "Failed to unmute notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Unmuted"})
+}
+
+// unsubscribePage asks to confirm unsubscribing from email digests, then confirms it was done.
+// The form posts back to the same URL.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Email digests</title></head>
+<body>
+{{if .Done}}
+<p>You've been unsubscribed from email digests.</p>
+{{else}}
+<p>Stop receiving email digests of your unread notifications?</p>
+<form method="post"><button type="submit">Unsubscribe</button></form>
+{{end}}
+</body>
+</html>
+`))
+
+// ConfirmUnsubscribeEmailHandler shows the page behind the link in a digest email. Opening the
+// link changes nothing, as mail scanners and link previews fetch it; the page's form POSTs to
+// UnsubscribeEmailHandler.
+func ConfirmUnsubscribeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	_, err := repositories.NewNotificationSettingsRepository(config.GetDB()).GetUnsubscribeTokenUser(token)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Unsubscribe link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error loading unsubscribe token:", err)
+		http.Error(w, "Failed to load unsubscribe link", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	unsubscribePage.Execute(w, struct{ Done bool }{false})
+}
+
+// UnsubscribeEmailHandler turns off email digests, from the confirmation page's form or a mail
+// client's one-click unsubscribe. Mail clients can't send session cookies, so the secret token
+// in the URL is the only credential.
+func UnsubscribeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	userID, err := repositories.NewNotificationSettingsRepository(config.GetDB()).UnsubscribeEmailDigest(token)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Unsubscribe link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error unsubscribing from email digests:", err)
+		http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("📧 User %d unsubscribed from email digests", userID)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	unsubscribePage.Execute(w, struct{ Done bool }{true})
+}
+
+// AcceptNotificationHandler accepts the follow or group join request behind a notification
+func AcceptNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	respondToNotification(w, r, true)
+}
+
+// DeclineNotificationHandler declines the follow or group join request behind a notification
+func DeclineNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	respondToNotification(w, r, false)
+}
+
+// respondToNotification resolves the request an actionable notification points at and returns
+// the notification in its new state
+func respondToNotification(w http.ResponseWriter, r *http.Request, accept bool) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	db := config.GetDB()
+	repo := repositories.NewNotificationRepository(db)
+	notificationID := pathID(r, "id")
+	notification, err := repo.GetNotification(userID, notificationID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error fetching notification:", err)
+		http.Error(w, "Failed to retrieve notification", http.StatusInternalServerError)
+		return
+	}
+	if !models.IsActionable(notification.Type) {
+		http.Error(w, "This notification has no actions", http.StatusBadRequest)
+		return
+	}
+	if notification.State != models.NotificationStatePending {
+		http.Error(w, "This request was already "+notification.State, http.StatusConflict)
+		return
+	}
+
+	switch notification.Type {
+	case models.NotificationTypeFollowRequest:
+		err = answerFollowRequest(db, notification.EntityID, userID, accept)
+	case models.NotificationTypeGroupJoinRequest:
+		err = answerJoinRequest(db, notification.EntityID, notification.ActorID, userID, accept)
+	}
+	if err == repositories.ErrNoPendingFollow || err == repositories.ErrNoPendingMembership {
+		http.Error(w, "This request no longer exists", http.StatusGone)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error responding to request:", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	notification, err = repo.GetNotification(userID, notificationID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notification)
+}
+
+// resolveRequestNotifications records a request's outcome on every notification about it and
+// pushes the new unread counts to the recipients
+func resolveRequestNotifications(db *sql.DB, notifType, entityType string, entityID, actorID int, state string) {
+	userIDs, err := repositories.NewNotificationRepository(db).
+		ResolveRequestNotifications(notifType, entityType, entityID, actorID, state)
+	if err != nil {
+		log.Println("❌ Error resolving request notifications:", err)
+		return
+	}
+	for _, userID := range userIDs {
+		ws.PushUnreadCount(userID)
+	}
+}
+
+// GetNotificationSettingsHandler returns the authenticated user's notification settings
+func GetNotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	repo := repositories.NewNotificationSettingsRepository(config.GetDB())
+	settings, err := repo.GetSettings(userID)
+	if err != nil {
+		log.Println("❌ Error fetching notification settings:", err)
+		http.Error(w, "Failed to retrieve notification settings", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "All notifications marked as read"})
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateNotificationSettingsHandler changes the authenticated user's notification settings
+func UpdateNotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody struct {
+		RSVPDigest      *bool `json:"rsvp_digest"`
+		ReminderOffsets []int `json:"reminder_offsets"` // Minutes before events; [] turns reminders off
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	offsets, ok := normalizeReminderOffsets(requestBody.ReminderOffsets)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Reminder offsets must be between 1 and %d minutes, at most %d of them",
+			models.MaxReminderOffset, models.MaxReminderOffsets), http.StatusBadRequest)
+		return
+	}
+
+	repo := repositories.NewNotificationSettingsRepository(config.GetDB())
+	if requestBody.ReminderOffsets != nil {
+		if err := repo.SetReminderOffsets(userID, offsets); err != nil {
+			log.Println("❌ Error updating reminder offsets:", err)
+			http.Error(w, "Failed to update notification settings", http.StatusInternalServerError)
+			return
+		}
+	}
+	if requestBody.RSVPDigest != nil {
+		if err := repo.SetRSVPDigest(userID, *requestBody.RSVPDigest); err != nil {
+			log.Println("❌ Error updating notification settings:", err)
+			http.Error(w, "Failed to update notification settings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	settings, err := repo.GetSettings(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve notification settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+// normalizeReminderOffsets validates reminder offsets and returns them deduplicated, earliest reminder first
+func normalizeReminderOffsets(offsets []int) ([]int, bool) {
+	if len(offsets) > models.MaxReminderOffsets {
+		return nil, false
+	}
+	seen := make(map[int]bool)
+	normalized := []int{}
+	for _, offset := range offsets {
+		if offset < 1 || offset > models.MaxReminderOffset {
+			return nil, false
+		}
+		if !seen[offset] {
+			seen[offset] = true
+			normalized = append(normalized, offset)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(normalized)))
+	return normalized, true
 }
@@ -10,6 +10,7 @@ import (
 	"social-network/internal/middlewares"
 	"social-network/internal/models"
 	"social-network/internal/repositories"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -30,6 +31,10 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	if user.Avatar != nil && !strings.HasPrefix(*user.Avatar, "/uploads/") {
+		http.Error(w, "Avatar must be an uploaded image under /uploads/", http.StatusBadRequest)
+		return
+	}
 
 	// Hash password before storing
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
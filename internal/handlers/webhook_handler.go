@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"social-network/internal/config"
+	"social-network/internal/middlewares"
+	"social-network/internal/models"
+	"social-network/internal/repositories"
+	"social-network/internal/webhooks"
+)
+
+// webhookRequest is the body accepted when creating or updating a webhook
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"` // Only used on update
+}
+
+// validate checks the URL and events, writing an error and returning false if they're invalid
+func (req *webhookRequest) validate(w http.ResponseWriter) bool {
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		http.Error(w, "Invalid webhook URL: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if len(req.Events) == 0 {
+		http.Error(w, "At least one event is required", http.StatusBadRequest)
+		return false
+	}
+	seen := make(map[string]bool)
+	events := req.Events[:0]
+	for _, event := range req.Events {
+		if !models.IsWebhookEvent(event) {
+			http.Error(w, "Unknown event: "+event, http.StatusBadRequest)
+			return false
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	req.Events = events
+	return true
+}
+
+// CreateWebhookHandler lets a group admin register an endpoint for the group's events. The
+// response includes the signing secret, which isn't shown again.
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !requestBody.validate(w) {
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+
+	webhook := models.Webhook{
+		GroupID:   groupID,
+		CreatedBy: userID,
+		URL:       requestBody.URL,
+		Events:    requestBody.Events,
+	}
+	repo := repositories.NewWebhookRepository(db)
+	if err := repo.CreateWebhook(&webhook); err != nil {
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// GetWebhooksHandler lists a group's webhooks for its admins
+func GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	if groupID == 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+
+	webhooks, err := repositories.NewWebhookRepository(db).GetGroupWebhooks(groupID)
+	if err != nil {
+		log.Println("❌ Error fetching webhooks:", err)
+		http.Error(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// UpdateWebhookHandler changes a webhook's URL and events, or pauses it with "active": false.
+// Deliveries queued while a webhook is paused are sent once it's active again.
+func UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	webhookID := pathID(r, "webhookID")
+	if groupID == 0 || webhookID == 0 {
+		http.Error(w, "Invalid group or webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	var requestBody webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+
+	webhook, ok := loadWebhook(w, db, groupID, webhookID)
+	if !ok {
+		return
+	}
+
+	// Omitted fields keep their current values
+	if requestBody.URL == "" {
+		requestBody.URL = webhook.URL
+	}
+	if requestBody.Events == nil {
+		requestBody.Events = webhook.Events
+	}
+	if !requestBody.validate(w) {
+		return
+	}
+	webhook.URL = requestBody.URL
+	webhook.Events = requestBody.Events
+	if requestBody.Active != nil {
+		webhook.Active = *requestBody.Active
+	}
+
+	if err := repositories.NewWebhookRepository(db).UpdateWebhook(webhook); err != nil {
+		log.Println("❌ Error updating webhook:", err)
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	webhook.Secret = ""
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhookHandler removes a webhook and its delivery history
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	webhookID := pathID(r, "webhookID")
+	if groupID == 0 || webhookID == 0 {
+		http.Error(w, "Invalid group or webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+
+	err := repositories.NewWebhookRepository(db).DeleteWebhook(groupID, webhookID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error deleting webhook:", err)
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted"})
+}
+
+// GetWebhookDeliveriesHandler pages through a webhook's deliveries, newest first, with
+// ?limit= (default 20, max 100) and ?before_id=
+func GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	webhookID := pathID(r, "webhookID")
+	if groupID == 0 || webhookID == 0 {
+		http.Error(w, "Invalid group or webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, beforeID := 20, 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 100 {
+			http.Error(w, "Limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	if value := r.URL.Query().Get("before_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "Invalid before_id", http.StatusBadRequest)
+			return
+		}
+		beforeID = parsed
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+	if _, ok := loadWebhook(w, db, groupID, webhookID); !ok {
+		return
+	}
+
+	deliveries, err := repositories.NewWebhookRepository(db).GetWebhookDeliveries(webhookID, beforeID, limit)
+	if err != nil {
+		log.Println("❌ Error fetching webhook deliveries:", err)
+		http.Error(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// GetWebhookDeliveryHandler returns a delivery with every attempt made for it
+func GetWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	webhookID := pathID(r, "webhookID")
+	deliveryID := pathID(r, "deliveryID")
+	if groupID == 0 || webhookID == 0 || deliveryID == 0 {
+		http.Error(w, "Invalid group, webhook or delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+	if _, ok := loadWebhook(w, db, groupID, webhookID); !ok {
+		return
+	}
+
+	delivery, err := repositories.NewWebhookRepository(db).GetDelivery(webhookID, deliveryID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error fetching webhook delivery:", err)
+		http.Error(w, "Failed to fetch delivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// RedeliverWebhookHandler queues a delivery to be sent again with its original payload, e.g.
+// after fixing the receiving endpoint. The copy gets a new ID and a fresh set of attempts.
+func RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserIDFromSession(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID := pathID(r, "id")
+	webhookID := pathID(r, "webhookID")
+	deliveryID := pathID(r, "deliveryID")
+	if groupID == 0 || webhookID == 0 || deliveryID == 0 {
+		http.Error(w, "Invalid group, webhook or delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	db := config.GetDB()
+	if !requireGroupPermission(w, db, userID, groupID, models.GroupPermManageWebhooks) {
+		return
+	}
+	if _, ok := loadWebhook(w, db, groupID, webhookID); !ok {
+		return
+	}
+
+	delivery, err := repositories.NewWebhookRepository(db).Redeliver(webhookID, deliveryID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("❌ Error queueing redelivery:", err)
+		http.Error(w, "Failed to queue redelivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// loadWebhook fetches one of a group's webhooks, writing a 404 if it doesn't exist
+func loadWebhook(w http.ResponseWriter, db *sql.DB, groupID, webhookID int) (*models.Webhook, bool) {
+	webhook, err := repositories.NewWebhookRepository(db).GetWebhook(groupID, webhookID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Println("❌ Error fetching webhook:", err)
+		http.Error(w, "Failed to fetch webhook", http.StatusInternalServerError)
+		return nil, false
+	}
+	return webhook, true
+}
+
+// queueWebhookEvent queues an event for the group's subscribed webhooks. Failures are only
+// logged, as they shouldn't fail the request that triggered the event.
+func queueWebhookEvent(db *sql.DB, groupID int, event string, data interface{}) {
+	queued, err := repositories.NewWebhookRepository(db).EnqueueEvent(groupID, event, data)
+	if err != nil {
+		log.Printf("❌ Failed to queue %s webhooks for Group %d: %v", event, groupID, err)
+		return
+	}
+	if queued > 0 {
+		log.Printf("🔗 Queued %s for %d webhook(s) of Group %d", event, queued, groupID)
+	}
+}
+
+// queueMemberJoinedWebhook queues a group_member.joined event; via says how the user joined
+func queueMemberJoinedWebhook(db *sql.DB, groupID, userID int, via string) {
+	data := map[string]interface{}{"group_id": groupID, "user_id": userID, "via": via}
+	if nickname, err := repositories.NewUserRepository(db).GetNickname(userID); err == nil {
+		data["nickname"] = nickname
+	}
+	queueWebhookEvent(db, groupID, models.WebhookEventMemberJoined, data)
+}
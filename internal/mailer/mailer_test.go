@@ -0,0 +1,208 @@
+package mailer
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receivedMail is what the test SMTP server was sent in one session
+type receivedMail struct {
+	Auth string // Decoded AUTH PLAIN credentials, "\x00user\x00password"
+	From string
+	To   []string
+	Data string
+}
+
+// startSMTPServer runs a minimal SMTP server on a loopback port. It advertises AUTH PLAIN and
+// rejects recipients listed in reject.
+func startSMTPServer(t *testing.T, reject ...string) (host string, port int, mails <-chan receivedMail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan receivedMail, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, reject, received)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func serveSMTP(conn net.Conn, reject []string, received chan<- receivedMail) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tp := textproto.NewConn(conn)
+
+	var mail receivedMail
+	tp.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case strings.HasPrefix(command, "AUTH PLAIN "):
+			credentials, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			mail.Auth = string(credentials)
+			tp.PrintfLine("235 Authentication successful")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			mail.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			to := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			if contains(reject, to) {
+				tp.PrintfLine("550 No such user")
+				continue
+			}
+			mail.To = append(mail.To, to)
+			tp.PrintfLine("250 OK")
+		case command == "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+			tp.PrintfLine("250 OK")
+			received <- mail
+		case command == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	host, port, mails := startSMTPServer(t)
+	mailer := &SMTPMailer{Host: host, Port: port, From: "no-reply@example.com"}
+
+	msg := Message{
+		To:      "alice@example.com",
+		Subject: "You have 2 unread notifications ✉",
+		Text:    "Plain text body",
+		HTML:    "<p>HTML body</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<http://localhost:8080/email/unsubscribe/abc>"},
+	}
+	if err := mailer.Send(msg); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	var got receivedMail
+	select {
+	case got = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the SMTP server received no message")
+	}
+	if got.Auth != "" {
+		t.Errorf("authenticated as %q without a username", got.Auth)
+	}
+	if got.From != "no-reply@example.com" || len(got.To) != 1 || got.To[0] != "alice@example.com" {
+		t.Errorf("envelope = %s -> %v, want no-reply@example.com -> [alice@example.com]", got.From, got.To)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("the message isn't a valid email: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != msg.Headers["List-Unsubscribe"] {
+		t.Errorf("List-Unsubscribe = %q, want %q", got, msg.Headers["List-Unsubscribe"])
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s (%v), want multipart/alternative", mediaType, err)
+	}
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", want.contentType, err)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if part.Header.Get("Content-Type") != want.contentType || string(body) != want.body {
+			t.Errorf("part = %s %q, want %s %q", part.Header.Get("Content-Type"), body, want.contentType, want.body)
+		}
+	}
+}
+
+func TestSMTPMailerAuthenticates(t *testing.T) {
+	host, port, mails := startSMTPServer(t)
+	mailer := &SMTPMailer{Host: host, Port: port, Username: "user", Password: "secret", From: "no-reply@example.com"}
+
+	if err := mailer.Send(Message{To: "alice@example.com", Subject: "Hi", Text: "Hi", HTML: "Hi"}); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	select {
+	case got := <-mails:
+		if got.Auth != "\x00user\x00secret" {
+			t.Errorf("AUTH PLAIN credentials = %q, want user and secret", got.Auth)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the SMTP server received no message")
+	}
+}
+
+func TestSMTPMailerRejectedRecipient(t *testing.T) {
+	host, port, _ := startSMTPServer(t, "nobody@example.com")
+	mailer := &SMTPMailer{Host: host, Port: port, From: "no-reply@example.com"}
+
+	err := mailer.Send(Message{To: "nobody@example.com", Subject: "Hi", Text: "Hi", HTML: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Send() = %v, want the server's 550 error", err)
+	}
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	mailer := &SMTPMailer{Host: addr.IP.String(), Port: addr.Port, From: "no-reply@example.com"}
+	if err := mailer.Send(Message{To: "alice@example.com", Subject: "Hi"}); err == nil {
+		t.Error("Send() succeeded without an SMTP server")
+	}
+}
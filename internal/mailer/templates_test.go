@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"social-network/internal/models"
+)
+
+// digestData has the fields the digest templates use
+type digestData struct {
+	Nickname         string
+	Notifications    []models.Notification
+	Total            int
+	More             int
+	NotificationsURL string
+	UnsubscribeURL   string
+}
+
+func TestComposeDigest(t *testing.T) {
+	createdAt := time.Date(2026, 3, 14, 9, 5, 0, 0, time.UTC)
+	data := digestData{
+		Nickname: "alice",
+		Notifications: []models.Notification{
+			{Message: "bob liked your post", CreatedAt: createdAt},
+			{Message: "<script>carol</script> commented", CreatedAt: createdAt},
+		},
+		Total:            5,
+		More:             3,
+		NotificationsURL: "http://localhost:8080/notifications",
+		UnsubscribeURL:   "http://localhost:8080/email/unsubscribe/abc123",
+	}
+
+	msg, err := Compose("alice@example.com", "You have 5 unread notifications", "digest", data)
+	if err != nil {
+		t.Fatalf("Compose() = %v", err)
+	}
+	if msg.To != "alice@example.com" || msg.Subject != "You have 5 unread notifications" {
+		t.Errorf("message = %s %q, want the given recipient and subject", msg.To, msg.Subject)
+	}
+
+	for _, want := range []string{
+		"Hi alice,",
+		"You have 5 unread notifications since your last digest:",
+		"- bob liked your post (Mar 14, 09:05)",
+		"- <script>carol</script> commented (Mar 14, 09:05)",
+		"...and 3 more.",
+		"See them all: http://localhost:8080/notifications",
+		"Unsubscribe: http://localhost:8080/email/unsubscribe/abc123",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text version is missing %q:\n%s", want, msg.Text)
+		}
+	}
+
+	for _, want := range []string{
+		"<p>Hi alice,</p>",
+		"<li>bob liked your post",
+		"&lt;script&gt;carol&lt;/script&gt; commented",
+		"…and 3 more.",
+		`<a href="http://localhost:8080/notifications">`,
+		`<a href="http://localhost:8080/email/unsubscribe/abc123">Unsubscribe</a>`,
+	} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("HTML version is missing %q:\n%s", want, msg.HTML)
+		}
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Error("HTML version doesn't escape notification messages")
+	}
+}
+
+func TestComposeDigestSingleNotification(t *testing.T) {
+	data := digestData{
+		Nickname:      "alice",
+		Notifications: []models.Notification{{Message: "bob followed you", CreatedAt: time.Now()}},
+		Total:         1,
+	}
+
+	msg, err := Compose("alice@example.com", "You have 1 unread notification", "digest", data)
+	if err != nil {
+		t.Fatalf("Compose() = %v", err)
+	}
+	if !strings.Contains(msg.Text, "You have 1 unread notification since") {
+		t.Errorf("text version doesn't use the singular:\n%s", msg.Text)
+	}
+	if strings.Contains(msg.Text, "more.") || strings.Contains(msg.HTML, "more.") {
+		t.Error("digest mentions more notifications when there are none")
+	}
+}
+
+func TestComposeUnknownTemplate(t *testing.T) {
+	if _, err := Compose("alice@example.com", "Hi", "missing", nil); err == nil {
+		t.Error("Compose() succeeded with a template that doesn't exist")
+	}
+}
@@ -3,9 +3,26 @@ package models
 import "time"
 
 type Comment struct {
-	ID        int       `json:"id"`
-	PostID    int       `json:"post_id"`
-	UserID    int       `json:"user_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int       `json:"id"`
+	PostID      int       `json:"post_id,omitempty"`
+	GroupPostID int       `json:"group_post_id,omitempty"` // Set instead of PostID for comments on group posts
+	ParentID    int       `json:"parent_id,omitempty"`     // The comment this one replies to, 0 for top-level comments
+	Depth       int       `json:"depth"`                   // 0 for top-level comments, parent's depth + 1 for replies
+	UserID      int       `json:"user_id"`
+	Nickname    string    `json:"nickname,omitempty"` // The author's
+	Avatar      *string   `json:"avatar,omitempty"`   // The author's, nil when unset
+	Content     string    `json:"content"`
+	LikeCount   int       `json:"like_count"`
+	ViewerLiked bool      `json:"viewer_liked"`      // Whether the user loading the comment liked it
+	ReplyCount  int       `json:"reply_count"`       // Direct replies, including those not embedded in Replies
+	Replies     []Comment `json:"replies,omitempty"` // The first CommentReplyPreview direct replies, oldest first
+	CreatedAt   time.Time `json:"created_at"`
 }
+
+const (
+	// MaxCommentDepth is how deeply replies may nest; a comment at this depth can't be replied to
+	MaxCommentDepth = 3
+	// CommentReplyPreview is how many replies are embedded under each comment. The rest are
+	// loaded page by page from the comment's replies endpoint.
+	CommentReplyPreview = 3
+)
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// maxOccurrenceScan bounds how many candidate dates are generated when expanding a series
+const maxOccurrenceScan = 5000
+
+// EventRecurrence describes how an event repeats. Dates are expanded in UTC.
+type EventRecurrence struct {
+	Freq       string   `json:"freq"`                 // daily, weekly or monthly
+	Interval   int      `json:"interval"`             // Repeat every N days/weeks/months
+	Until      string   `json:"until,omitempty"`      // Last allowed start, RFC 3339
+	Count      int      `json:"count,omitempty"`      // Total number of occurrences
+	Exceptions []string `json:"exceptions,omitempty"` // Occurrence dates that are skipped
+}
+
+// EventOccurrenceOverride changes or skips one occurrence of a recurring event.
+// Nil fields inherit the series value.
+type EventOccurrenceOverride struct {
+	OccurrenceDate string  `json:"occurrence_date"`
+	Excluded       bool    `json:"excluded"`
+	Title          *string `json:"title,omitempty"`
+	Description    *string `json:"description,omitempty"`
+	EventDate      *string `json:"event_date,omitempty"`
+}
+
+// Normalize validates the rule and rewrites its dates as RFC 3339 UTC
+func (r *EventRecurrence) Normalize() error {
+	switch r.Freq {
+	case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+	default:
+		return fmt.Errorf("frequency must be daily, weekly or monthly")
+	}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("interval must be at least 1")
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("count can't be negative")
+	}
+	if r.Count > 0 && r.Until != "" {
+		return fmt.Errorf("use either until or count, not both")
+	}
+	if r.Until != "" {
+		until, err := ParseEventDate(r.Until)
+		if err != nil {
+			return fmt.Errorf("invalid until date")
+		}
+		r.Until = OccurrenceKey(until)
+	}
+	for i, exception := range r.Exceptions {
+		t, err := ParseEventDate(exception)
+		if err != nil {
+			return fmt.Errorf("invalid exception date %q", exception)
+		}
+		r.Exceptions[i] = OccurrenceKey(t)
+	}
+	return nil
+}
+
+// candidate returns the n-th start of the series; ok is false for dates that don't exist,
+// such as the 31st in a shorter month, which are skipped like in iCalendar
+func (r *EventRecurrence) candidate(start time.Time, n int) (time.Time, bool) {
+	switch r.Freq {
+	case RecurrenceDaily:
+		return start.AddDate(0, 0, n*r.Interval), true
+	case RecurrenceWeekly:
+		return start.AddDate(0, 0, 7*n*r.Interval), true
+	default:
+		t := start.AddDate(0, n*r.Interval, 0)
+		return t, t.Day() == start.Day()
+	}
+}
+
+// OccurrenceKey formats an occurrence's scheduled start the way it's stored and sent to clients
+func OccurrenceKey(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Occurrences returns the scheduled starts of the event between from and to, inclusive.
+// A one-off event has a single occurrence. Excluded occurrences are still returned.
+func (e *GroupEvent) Occurrences(from, to time.Time) ([]time.Time, error) {
+	start, err := e.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	if e.Recurrence == nil {
+		if start.Before(from) || start.After(to) {
+			return nil, nil
+		}
+		return []time.Time{start}, nil
+	}
+
+	var until time.Time
+	if e.Recurrence.Until != "" {
+		if until, err = ParseEventDate(e.Recurrence.Until); err != nil {
+			return nil, err
+		}
+	}
+
+	var occurrences []time.Time
+	produced := 0
+	for n := 0; n < maxOccurrenceScan; n++ {
+		t, ok := e.Recurrence.candidate(start, n)
+		if !ok {
+			continue
+		}
+		if (!until.IsZero() && t.After(until)) || (e.Recurrence.Count > 0 && produced >= e.Recurrence.Count) || t.After(to) {
+			break
+		}
+		produced++
+		if !t.Before(from) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences, nil
+}
+
+// HasOccurrence reports whether the series is scheduled to start at the given time
+func (e *GroupEvent) HasOccurrence(t time.Time) bool {
+	occurrences, err := e.Occurrences(t, t)
+	return err == nil && len(occurrences) == 1
+}
+
+// IsScheduledAt reports whether an occurrence key, as stored with RSVPs and occurrence changes,
+// names an occurrence of the event: "" for a one-off event, a scheduled start for a series
+func (e *GroupEvent) IsScheduledAt(key string) bool {
+	if e.Recurrence == nil {
+		return key == ""
+	}
+	t, err := ParseEventDate(key)
+	return err == nil && e.HasOccurrence(t)
+}
+
+// AtOccurrence returns a copy of the event describing a single occurrence, with the override applied
+func (e *GroupEvent) AtOccurrence(t time.Time, override *EventOccurrenceOverride) GroupEvent {
+	occurrence := *e
+	occurrence.OccurrenceDate = OccurrenceKey(t)
+	occurrence.EventDate = occurrence.OccurrenceDate
+	if override != nil {
+		if override.Title != nil {
+			occurrence.Title = *override.Title
+		}
+		if override.Description != nil {
+			occurrence.Description = *override.Description
+		}
+		if override.EventDate != nil {
+			occurrence.EventDate = *override.EventDate
+		}
+	}
+	return occurrence
+}
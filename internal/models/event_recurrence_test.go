@@ -0,0 +1,164 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := ParseEventDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestOccurrences(t *testing.T) {
+	forever := date("2100-01-01T00:00:00Z")
+	tests := []struct {
+		name       string
+		start      string
+		recurrence *EventRecurrence
+		from, to   time.Time
+		want       []string
+	}{
+		{
+			name:  "one-off inside the window",
+			start: "2027-01-04T10:00:00Z",
+			from:  time.Time{}, to: forever,
+			want: []string{"2027-01-04T10:00:00Z"},
+		},
+		{
+			name:  "one-off outside the window",
+			start: "2027-01-04T10:00:00Z",
+			from:  date("2027-01-05T00:00:00Z"), to: forever,
+			want: nil,
+		},
+		{
+			name:       "daily every other day with a count",
+			start:      "2027-01-01T09:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceDaily, Interval: 2, Count: 3},
+			from:       time.Time{}, to: forever,
+			want: []string{"2027-01-01T09:00:00Z", "2027-01-03T09:00:00Z", "2027-01-05T09:00:00Z"},
+		},
+		{
+			name:       "weekly with a count",
+			start:      "2027-01-04T10:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceWeekly, Interval: 1, Count: 3},
+			from:       time.Time{}, to: forever,
+			want: []string{"2027-01-04T10:00:00Z", "2027-01-11T10:00:00Z", "2027-01-18T10:00:00Z"},
+		},
+		{
+			name:       "monthly from the 31st skips shorter months",
+			start:      "2027-01-31T18:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceMonthly, Interval: 1, Count: 4},
+			from:       time.Time{}, to: forever,
+			want: []string{"2027-01-31T18:00:00Z", "2027-03-31T18:00:00Z", "2027-05-31T18:00:00Z", "2027-07-31T18:00:00Z"},
+		},
+		{
+			name:       "monthly from the 30th skips February",
+			start:      "2027-01-30T18:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceMonthly, Interval: 1, Count: 3},
+			from:       time.Time{}, to: forever,
+			want: []string{"2027-01-30T18:00:00Z", "2027-03-30T18:00:00Z", "2027-04-30T18:00:00Z"},
+		},
+		{
+			name:       "monthly from the 29th includes a leap day",
+			start:      "2028-01-29T18:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceMonthly, Interval: 1, Count: 3},
+			from:       time.Time{}, to: forever,
+			want: []string{"2028-01-29T18:00:00Z", "2028-02-29T18:00:00Z", "2028-03-29T18:00:00Z"},
+		},
+		{
+			name:       "until is inclusive",
+			start:      "2027-01-31T18:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceMonthly, Interval: 1, Until: "2027-05-31T18:00:00Z"},
+			from:       time.Time{}, to: forever,
+			want: []string{"2027-01-31T18:00:00Z", "2027-03-31T18:00:00Z", "2027-05-31T18:00:00Z"},
+		},
+		{
+			name:       "until before the second occurrence",
+			start:      "2027-01-04T10:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceWeekly, Interval: 1, Until: "2027-01-10T23:59:59Z"},
+			from:       time.Time{}, to: forever,
+			want: []string{"2027-01-04T10:00:00Z"},
+		},
+		{
+			name:       "count includes occurrences before the window",
+			start:      "2027-01-01T09:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceDaily, Interval: 1, Count: 5},
+			from:       date("2027-01-03T00:00:00Z"), to: forever,
+			want: []string{"2027-01-03T09:00:00Z", "2027-01-04T09:00:00Z", "2027-01-05T09:00:00Z"},
+		},
+		{
+			name:       "window ends before the count runs out",
+			start:      "2027-01-01T09:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceDaily, Interval: 1, Count: 10},
+			from:       time.Time{}, to: date("2027-01-02T09:00:00Z"),
+			want: []string{"2027-01-01T09:00:00Z", "2027-01-02T09:00:00Z"},
+		},
+		{
+			name:  "exceptions are still returned",
+			start: "2027-01-04T10:00:00Z",
+			recurrence: &EventRecurrence{Freq: RecurrenceWeekly, Interval: 1, Count: 3,
+				Exceptions: []string{"2027-01-11T10:00:00Z"}},
+			from: time.Time{}, to: forever,
+			want: []string{"2027-01-04T10:00:00Z", "2027-01-11T10:00:00Z", "2027-01-18T10:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &GroupEvent{EventDate: tt.start, Recurrence: tt.recurrence}
+			occurrences, err := event.Occurrences(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Occurrences() = %v", err)
+			}
+			var got []string
+			for _, o := range occurrences {
+				got = append(got, OccurrenceKey(o))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesInvalidDates(t *testing.T) {
+	event := &GroupEvent{EventDate: "next tuesday"}
+	if _, err := event.Occurrences(time.Time{}, time.Now()); err == nil {
+		t.Error("Occurrences() accepted an unparseable event date")
+	}
+
+	event = &GroupEvent{EventDate: "2027-01-04T10:00:00Z", Recurrence: &EventRecurrence{Freq: RecurrenceDaily, Interval: 1, Until: "soon"}}
+	if _, err := event.Occurrences(time.Time{}, time.Now()); err == nil {
+		t.Error("Occurrences() accepted an unparseable until date")
+	}
+}
+
+func TestIsScheduledAt(t *testing.T) {
+	oneOff := &GroupEvent{EventDate: "2027-01-04T10:00:00Z"}
+	series := &GroupEvent{EventDate: "2027-01-04T10:00:00Z", Recurrence: &EventRecurrence{Freq: RecurrenceWeekly, Interval: 1, Count: 3}}
+
+	tests := []struct {
+		name  string
+		event *GroupEvent
+		key   string
+		want  bool
+	}{
+		{"one-off RSVP", oneOff, "", true},
+		{"occurrence key on a one-off", oneOff, "2027-01-04T10:00:00Z", false},
+		{"one-off RSVP on a series", series, "", false},
+		{"first occurrence", series, "2027-01-04T10:00:00Z", true},
+		{"last occurrence", series, "2027-01-18T10:00:00Z", true},
+		{"past the count", series, "2027-01-25T10:00:00Z", false},
+		{"between occurrences", series, "2027-01-05T10:00:00Z", false},
+		{"wrong time of day", series, "2027-01-11T11:00:00Z", false},
+	}
+	for _, tt := range tests {
+		if got := tt.event.IsScheduledAt(tt.key); got != tt.want {
+			t.Errorf("%s: IsScheduledAt(%q) = %v, want %v", tt.name, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package models
+
+// EventReminderCandidate is a user who answered going or maybe to an upcoming event.
+// For recurring events, Event describes the occurrence they answered.
+type EventReminderCandidate struct {
+	UserID int
+	Event  GroupEvent
+}
@@ -1,10 +1,76 @@
 package models
 
+import "fmt"
+
+// RSVP statuses. Waitlisted is never chosen by a user: "going" becomes waitlisted when the event is full.
+const (
+	RSVPStatusGoing      = "going"
+	RSVPStatusNotGoing   = "not going"
+	RSVPStatusInterested = "interested"
+	RSVPStatusMaybe      = "maybe"
+	RSVPStatusWaitlisted = "waitlisted"
+)
+
+// RSVPStatuses lists every status, in the order used for breakdowns
+var RSVPStatuses = []string{RSVPStatusGoing, RSVPStatusMaybe, RSVPStatusInterested, RSVPStatusWaitlisted, RSVPStatusNotGoing}
+
+// ValidRSVPChoice reports whether a user may pick the status when responding
+func ValidRSVPChoice(status string) bool {
+	switch status {
+	case RSVPStatusGoing, RSVPStatusNotGoing, RSVPStatusInterested, RSVPStatusMaybe:
+		return true
+	}
+	return false
+}
+
 // EventRSVP represents a user's RSVP status for an event
 type EventRSVP struct {
 	ID        int    `json:"id"`
 	EventID   int    `json:"event_id"`
 	UserID    int    `json:"user_id"`
-	Status    string `json:"status"` // "going" or "not going"
+	Status    string `json:"status"` // going, not going, interested, maybe or waitlisted
 	CreatedAt string `json:"created_at"`
 }
+
+// EventAttendee is a user who responded to an event
+type EventAttendee struct {
+	UserID   int    `json:"user_id"`
+	Nickname string `json:"nickname"`
+}
+
+// RSVPBreakdown counts an event's responses per status
+type RSVPBreakdown struct {
+	EventID        int            `json:"event_id"`
+	OccurrenceDate string         `json:"occurrence_date,omitempty"`
+	Capacity       *int           `json:"capacity,omitempty"`
+	Counts         map[string]int `json:"counts"`
+}
+
+// RSVPActivity is one response to an event, as reported to the event's creator
+type RSVPActivity struct {
+	EventID    int    `json:"event_id"`
+	EventTitle string `json:"event_title"`
+	UserID     int    `json:"user_id"`
+	Nickname   string `json:"nickname"`
+	Status     string `json:"status"`
+}
+
+// Describe renders the response as a sentence, e.g. `alice is going to "Picnic"`
+func (a RSVPActivity) Describe() string {
+	var verb string
+	switch a.Status {
+	case RSVPStatusGoing:
+		verb = "is going to"
+	case RSVPStatusNotGoing:
+		verb = "isn't going to"
+	case RSVPStatusInterested:
+		verb = "is interested in"
+	case RSVPStatusMaybe:
+		verb = "might go to"
+	case RSVPStatusWaitlisted:
+		verb = "joined the waitlist for"
+	default:
+		verb = "responded to"
+	}
+	return fmt.Sprintf("%s %s \"%s\"", a.Nickname, verb, a.EventTitle)
+}
@@ -10,10 +10,41 @@ type GroupMember struct {
 	JoinedAt time.Time `json:"joined_at"` // Fix: Add "joined_at" field
 }
 
+// Group privacy levels
+const (
+	GroupPrivacyPublic  = "public"
+	GroupPrivacyPrivate = "private"
+)
+
 type Group struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatorID   int       `json:"creator_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	CreatorID   int        `json:"creator_id"`
+	Privacy     string     `json:"privacy"` // "public" (instant join) or "private" (approval required)
+	CreatedAt   time.Time  `json:"created_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"` // Nil while the group is active
+}
+
+// GroupOwnershipTransfer represents an owner's offer to hand a group over to an admin
+type GroupOwnershipTransfer struct {
+	ID          int        `json:"id"`
+	GroupID     int        `json:"group_id"`
+	FromUserID  int        `json:"from_user_id"`
+	ToUserID    int        `json:"to_user_id"`
+	Status      string     `json:"status"` // "pending", "accepted", "declined" or "cancelled"
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// GroupBan represents a user barred from a group, optionally until a given time
+type GroupBan struct {
+	ID        int        `json:"id"`
+	GroupID   int        `json:"group_id"`
+	UserID    int        `json:"user_id"`
+	Nickname  string     `json:"nickname"`
+	BannedBy  int        `json:"banned_by"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Nil means the ban is permanent
+	CreatedAt time.Time  `json:"created_at"`
 }
@@ -1,12 +1,65 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 // GroupEvent represents an event inside a group
 type GroupEvent struct {
-	ID          int    `json:"id"`
-	GroupID     int    `json:"group_id"`
-	CreatorID   int    `json:"creator_id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	EventDate   string `json:"event_date"`
-	CreatedAt   string `json:"created_at"`
+	ID             int              `json:"id"`
+	GroupID        int              `json:"group_id"`
+	CreatorID      int              `json:"creator_id"`
+	Title          string           `json:"title"`
+	Description    string           `json:"description"`
+	EventDate      string           `json:"event_date"`
+	Capacity       *int             `json:"capacity,omitempty"`        // Nil means unlimited
+	Sequence       int              `json:"sequence"`                  // Incremented on every change, for calendar clients
+	Recurrence     *EventRecurrence `json:"recurrence,omitempty"`      // Nil for one-off events
+	OccurrenceDate string           `json:"occurrence_date,omitempty"` // Set when this is one occurrence of a series
+	CreatedAt      string           `json:"created_at"`
+	UpdatedAt      *time.Time       `json:"updated_at,omitempty"`
+	CancelledAt    *time.Time       `json:"cancelled_at,omitempty"` // Nil unless the event was cancelled
+}
+
+// GroupEventDetail is an event together with its attendees grouped by RSVP status
+type GroupEventDetail struct {
+	GroupEvent
+	Attendees map[string][]EventAttendee `json:"attendees"`
+}
+
+// NotificationData is the payload attached to notifications about the event
+func (e *GroupEvent) NotificationData() map[string]interface{} {
+	data := map[string]interface{}{"title": e.Title, "event_date": e.EventDate, "group_id": e.GroupID}
+	if e.OccurrenceDate != "" {
+		data["occurrence_date"] = e.OccurrenceDate
+	}
+	return data
+}
+
+// DefaultEventDuration is assumed for events, which only record a start time
+const DefaultEventDuration = time.Hour
+
+// eventDateLayouts are the formats accepted for event_date
+var eventDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseEventDate parses an event date; values without a zone are treated as UTC
+func ParseEventDate(value string) (time.Time, error) {
+	for _, layout := range eventDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid event date %q", value)
+}
+
+// StartTime returns the parsed event date
+func (e *GroupEvent) StartTime() (time.Time, error) {
+	return ParseEventDate(e.EventDate)
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// GroupInviteLink represents a shareable token that lets anyone join a group
+type GroupInviteLink struct {
+	ID        int        `json:"id"`
+	GroupID   int        `json:"group_id"`
+	Token     string     `json:"token"`
+	CreatedBy int        `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Nil means the link never expires
+	MaxUses   *int       `json:"max_uses,omitempty"`   // Nil means unlimited uses
+	Uses      int        `json:"uses"`
+	Revoked   bool       `json:"revoked"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+// Group roles, stored in group_members.status. "pending" is a join request, not a role.
+const (
+	GroupRolePending   = "pending"
+	GroupRoleMember    = "member"
+	GroupRoleModerator = "moderator"
+	GroupRoleAdmin     = "admin"
+	GroupRoleOwner     = "owner"
+)
+
+// Group permissions granted by roles
+const (
+	GroupPermApproveMembers = "approve_members"
+	GroupPermRemoveMembers  = "remove_members"
+	GroupPermDeletePosts    = "delete_posts"
+	GroupPermCreateEvents   = "create_events"
+	GroupPermManageChat     = "manage_chat"
+	GroupPermManageRoles    = "manage_roles"
+	GroupPermManageInvites  = "manage_invites"
+	GroupPermManageWebhooks = "manage_webhooks"
+	GroupPermEditGroup      = "edit_group"
+	GroupPermManageEvents   = "manage_events" // Edit and cancel events created by others
+)
+
+// groupRoleRanks orders roles from least to most privileged
+var groupRoleRanks = map[string]int{
+	GroupRoleMember:    1,
+	GroupRoleModerator: 2,
+	GroupRoleAdmin:     3,
+	GroupRoleOwner:     4,
+}
+
+// groupRolePermissions is the permission matrix for each role
+var groupRolePermissions = map[string][]string{
+	GroupRoleMember: {
+		GroupPermCreateEvents,
+	},
+	GroupRoleModerator: {
+		GroupPermCreateEvents, GroupPermApproveMembers, GroupPermDeletePosts, GroupPermManageChat,
+	},
+	GroupRoleAdmin: {
+		GroupPermCreateEvents, GroupPermApproveMembers, GroupPermDeletePosts, GroupPermManageChat,
+		GroupPermRemoveMembers, GroupPermManageRoles, GroupPermManageInvites, GroupPermManageWebhooks,
+		GroupPermEditGroup, GroupPermManageEvents,
+	},
+	GroupRoleOwner: {
+		GroupPermCreateEvents, GroupPermApproveMembers, GroupPermDeletePosts, GroupPermManageChat,
+		GroupPermRemoveMembers, GroupPermManageRoles, GroupPermManageInvites, GroupPermManageWebhooks,
+		GroupPermEditGroup, GroupPermManageEvents,
+	},
+}
+
+// GroupRoleRank returns the rank of a role; pending and unknown roles rank 0
+func GroupRoleRank(role string) int {
+	return groupRoleRanks[role]
+}
+
+// GroupRoleHasPermission reports whether a role grants a permission
+func GroupRoleHasPermission(role, permission string) bool {
+	for _, p := range groupRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.-]+)`)
+
+// MentionedNicknames returns the distinct nicknames mentioned as @nickname in a message, lowercased
+func MentionedNicknames(content string) []string {
+	seen := make(map[string]bool)
+	var nicknames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		nickname := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if nickname != "" && !seen[nickname] {
+			seen[nickname] = true
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	return nicknames
+}
@@ -1,13 +1,194 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
-// Notification represents a notification record
+// Notification represents a notification record. It's sent in this shape both by the REST
+// API and over the notification WebSocket.
 type Notification struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Type      string    `json:"type"`
-	Message   string    `json:"message"`
-	IsRead    bool      `json:"is_read"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            int                    `json:"id"`
+	UserID        int                    `json:"user_id"`
+	Type          string                 `json:"type"`
+	Message       string                 `json:"message"`
+	ActorID       int                    `json:"actor_id,omitempty"` // The user who caused it, if any
+	ActorNickname string                 `json:"actor_nickname,omitempty"`
+	ActorCount    int                    `json:"actor_count,omitempty"`   // How many users an aggregated notification covers
+	RecentActors  []NotificationActor    `json:"recent_actors,omitempty"` // The latest of them, newest first
+	EntityType    string                 `json:"entity_type,omitempty"`   // What it's about, see the NotificationEntity constants
+	EntityID      int                    `json:"entity_id,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`    // Extra details for rendering, e.g. an RSVP status
+	State         string                 `json:"state,omitempty"`   // For actionable notifications, see the NotificationState constants
+	Actions       []NotificationAction   `json:"actions,omitempty"` // What the recipient can do while the state is pending
+	IsRead        bool                   `json:"is_read"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Hidden        bool                   `json:"-"` // Kept for the email digest only, not listed in the app
 }
+
+// Entities a notification can point at
+const (
+	NotificationEntityPost          = "post"
+	NotificationEntityComment       = "comment"
+	NotificationEntityGroup         = "group"
+	NotificationEntityGroupPost     = "group_post"
+	NotificationEntityEvent         = "event"
+	NotificationEntityFollowRequest = "follow_request"
+	NotificationEntityGroupChat     = "group_chat"
+)
+
+// Notification types raised by activity on a user's content
+const (
+	NotificationTypeLike      = "like"
+	NotificationTypeComment   = "comment"
+	NotificationTypeReply     = "comment_reply"
+	NotificationTypeGroupPost = "group_post"
+	NotificationTypeMention   = "mention"
+)
+
+// NotificationActor is one of the users behind an aggregated notification
+type NotificationActor struct {
+	ID       int    `json:"id"`
+	Nickname string `json:"nickname"`
+}
+
+const (
+	// NotificationAggregationWindow is how long an unread like or comment notification keeps
+	// absorbing new actors before a fresh one is started
+	NotificationAggregationWindow = 24 * time.Hour
+	// MaxRecentActors is how many actors an aggregated notification lists
+	MaxRecentActors = 3
+)
+
+// IsAggregatable reports whether notifications of a type collapse per entity
+func IsAggregatable(notifType string) bool {
+	return notifType == NotificationTypeLike || notifType == NotificationTypeComment || notifType == NotificationTypeReply
+}
+
+// AggregatedMessage describes one or more users doing the same thing, e.g.
+// "alice and 12 others liked your post"
+func AggregatedMessage(latestActor string, actorCount int, action string) string {
+	switch {
+	case actorCount <= 1:
+		return fmt.Sprintf("%s %s", latestActor, action)
+	case actorCount == 2:
+		return fmt.Sprintf("%s and 1 other %s", latestActor, action)
+	default:
+		return fmt.Sprintf("%s and %d others %s", latestActor, actorCount-1, action)
+	}
+}
+
+// Notification types the recipient can accept or decline
+const (
+	NotificationTypeFollowRequest    = "follow_request"
+	NotificationTypeGroupJoinRequest = "group_join_request"
+)
+
+// States of an actionable notification
+const (
+	NotificationStatePending  = "pending"
+	NotificationStateAccepted = "accepted"
+	NotificationStateDeclined = "declined"
+)
+
+// NotificationAction is an inline action a client can offer next to a notification
+type NotificationAction struct {
+	Name   string `json:"name"` // "accept" or "decline"
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// IsActionable reports whether a notification type carries accept/decline actions
+func IsActionable(notifType string) bool {
+	return notifType == NotificationTypeFollowRequest || notifType == NotificationTypeGroupJoinRequest
+}
+
+// SetActions fills in the accept/decline actions while the notification is still pending
+func (n *Notification) SetActions() {
+	n.Actions = nil
+	if !IsActionable(n.Type) || n.State != NotificationStatePending {
+		return
+	}
+	for _, name := range []string{"accept", "decline"} {
+		n.Actions = append(n.Actions, NotificationAction{
+			Name:   name,
+			Method: "POST",
+			URL:    fmt.Sprintf("/api/notifications/%d/%s", n.ID, name),
+		})
+	}
+}
+
+// NotificationTypes lists every notification type users can set preferences for
+var NotificationTypes = []string{
+	NotificationTypeLike, NotificationTypeComment, NotificationTypeReply, NotificationTypeGroupPost, NotificationTypeMention,
+	NotificationTypeFollowRequest, "follow_accepted", "follow_declined",
+	NotificationTypeGroupJoinRequest, "group_join_accepted", "group_join_declined",
+	"group_ownership_transfer", "group_removed", "group_banned",
+	"event_created", "event_updated", "event_cancelled", "event_rsvp", "event_rsvp_digest",
+	"event_waitlist_promoted", "event_reminder",
+}
+
+// IsNotificationType reports whether a type is one of NotificationTypes
+func IsNotificationType(notifType string) bool {
+	for _, t := range NotificationTypes {
+		if t == notifType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationPreference holds the channels a user receives one notification type on
+type NotificationPreference struct {
+	Type        string `json:"type"`
+	InApp       bool   `json:"in_app"`       // Stored and listed in the app
+	Push        bool   `json:"push"`         // Sent live over the notification socket
+	EmailDigest bool   `json:"email_digest"` // Included in the daily email digest
+}
+
+// DefaultNotificationPreference is used for types the user hasn't configured
+func DefaultNotificationPreference(notifType string) NotificationPreference {
+	return NotificationPreference{Type: notifType, InApp: true, Push: true}
+}
+
+// Enabled reports whether the type is delivered on any channel
+func (p NotificationPreference) Enabled() bool {
+	return p.InApp || p.Push || p.EmailDigest
+}
+
+// NotificationMute silences notifications about a group, an event or a group chat
+type NotificationMute struct {
+	EntityType string    `json:"entity_type"` // "group", "event" or "group_chat"
+	EntityID   int       `json:"entity_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsMutableEntity reports whether notifications about an entity type can be muted
+func IsMutableEntity(entityType string) bool {
+	return entityType == NotificationEntityGroup || entityType == NotificationEntityEvent ||
+		entityType == NotificationEntityGroupChat
+}
+
+// EmailDigestRecipient is a user who gets notification digests by email
+type EmailDigestRecipient struct {
+	UserID   int
+	Email    string
+	Nickname string
+}
+
+// NotificationSettings holds a user's notification choices
+type NotificationSettings struct {
+	UserID          int   `json:"user_id"`
+	RSVPDigest      bool  `json:"rsvp_digest"`      // Receive RSVPs to your events as a periodic digest
+	ReminderOffsets []int `json:"reminder_offsets"` // Minutes before an event to send a reminder
+}
+
+// DefaultReminderOffsets remind users a day and an hour before their events
+var DefaultReminderOffsets = []int{24 * 60, 60}
+
+const (
+	// MaxReminderOffset is the earliest a reminder can be sent: one week before the event
+	MaxReminderOffset = 7 * 24 * 60
+	// MaxReminderOffsets caps how many reminders a user can ask for per event
+	MaxReminderOffsets = 5
+)
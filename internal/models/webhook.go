@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// Webhook events
+const (
+	WebhookEventGroupPostCreated  = "group_post.created"
+	WebhookEventGroupEventCreated = "group_event.created"
+	WebhookEventEventRSVP         = "group_event.rsvp"
+	WebhookEventMemberJoined      = "group_member.joined"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventGroupPostCreated,
+	WebhookEventGroupEventCreated,
+	WebhookEventEventRSVP,
+	WebhookEventMemberJoined,
+}
+
+// IsWebhookEvent reports whether a webhook can subscribe to the event
+func IsWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // Gave up after WebhookMaxAttempts
+)
+
+// WebhookMaxAttempts is how many times a delivery is tried before it's marked failed
+const WebhookMaxAttempts = 8
+
+// WebhookRetryDelay is the wait before retrying a delivery that has failed attempts times:
+// 30 seconds after the first failure, doubling each time up to six hours
+func WebhookRetryDelay(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts && delay < 6*time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > 6*time.Hour {
+		delay = 6 * time.Hour
+	}
+	return delay
+}
+
+// Webhook is an endpoint that receives a group's events as signed JSON POSTs
+type Webhook struct {
+	ID        int       `json:"id"`
+	GroupID   int       `json:"group_id"`
+	CreatedBy int       `json:"created_by"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the webhook is created
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook wants the event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the JSON body POSTed to webhooks
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	GroupID   int         `json:"group_id"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for one webhook
+type WebhookDelivery struct {
+	ID            int              `json:"id"`
+	WebhookID     int              `json:"webhook_id"`
+	Event         string           `json:"event"`
+	Payload       string           `json:"payload"`
+	Status        string           `json:"status"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty"` // Nil once delivered or failed
+	RedeliveryOf  int              `json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	AttemptLog    []WebhookAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookAttempt records one request made for a delivery
+type WebhookAttempt struct {
+	ID             int       `json:"id"`
+	DeliveryID     int       `json:"delivery_id"`
+	ResponseStatus int       `json:"response_status"` // 0 when no response was received
+	Error          string    `json:"error,omitempty"`
+	DurationMS     int       `json:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at"`
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import "database/sql"
+
+// CalendarTokenRepository handles the secret tokens behind calendar subscription feeds
+type CalendarTokenRepository struct {
+	DB *sql.DB
+}
+
+// NewCalendarTokenRepository creates a new instance of CalendarTokenRepository
+func NewCalendarTokenRepository(db *sql.DB) *CalendarTokenRepository {
+	return &CalendarTokenRepository{DB: db}
+}
+
+// GetOrCreateToken returns the user's feed token, creating one on first use
+func (repo *CalendarTokenRepository) GetOrCreateToken(userID int) (string, error) {
+	var token string
+	err := repo.DB.QueryRow(`SELECT token FROM calendar_tokens WHERE user_id = ?`, userID).Scan(&token)
+	if err != sql.ErrNoRows {
+		return token, err
+	}
+	return repo.ResetToken(userID)
+}
+
+// ResetToken replaces the user's feed token, so previously shared feed URLs stop working
+func (repo *CalendarTokenRepository) ResetToken(userID int) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = repo.DB.Exec(`
+        INSERT INTO calendar_tokens (user_id, token) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET token = excluded.token, created_at = CURRENT_TIMESTAMP`,
+		userID, token)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetUserIDByToken resolves a feed token to its owner; it returns sql.ErrNoRows for unknown tokens
+func (repo *CalendarTokenRepository) GetUserIDByToken(token string) (int, error) {
+	var userID int
+	err := repo.DB.QueryRow(`SELECT user_id FROM calendar_tokens WHERE token = ?`, token).Scan(&userID)
+	return userID, err
+}
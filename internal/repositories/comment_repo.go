@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"database/sql"
+	"math"
 	"social-network/internal/models"
+	"strings"
 )
 
 // CommentRepository handles comment-related database operations
@@ -15,42 +17,205 @@ func NewCommentRepository(db *sql.DB) *CommentRepository {
 	return &CommentRepository{DB: db}
 }
 
+// commentColumns lists the columns scanned by scanComment. Queries alias comments as c, join
+// commentAuthor and pass the viewer's user ID as their first argument.
+const commentColumns = `c.id, COALESCE(c.post_id, 0), COALESCE(c.group_post_id, 0), COALESCE(c.parent_id, 0),
+        c.depth, c.user_id, IFNULL(u.nickname, ''), u.avatar, c.content, c.created_at,
+        (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id),
+        (SELECT COUNT(*) FROM likes l WHERE l.comment_id = c.id),
+        EXISTS (SELECT 1 FROM likes l WHERE l.comment_id = c.id AND l.user_id = ?)`
+
+// commentAuthor joins the author so clients can show their nickname and avatar
+const commentAuthor = ` LEFT JOIN users u ON c.user_id = u.id `
+
+// scanComment reads a comment selected with commentColumns
+func scanComment(row rowScanner) (*models.Comment, error) {
+	var comment models.Comment
+	err := row.Scan(&comment.ID, &comment.PostID, &comment.GroupPostID, &comment.ParentID, &comment.Depth,
+		&comment.UserID, &comment.Nickname, &comment.Avatar, &comment.Content, &comment.CreatedAt,
+		&comment.ReplyCount, &comment.LikeCount, &comment.ViewerLiked)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// CommentPage selects a page of top-level comments. AfterID is the last comment of the
+// previous page, 0 for the first page.
+type CommentPage struct {
+	AfterID     int
+	Limit       int
+	NewestFirst bool
+}
+
+// AddComment stores a comment on either a post or a group post and fills in its ID. Replies
+// must have their ParentID and Depth set.
 func (repo *CommentRepository) AddComment(comment *models.Comment) error {
-	_, err := repo.DB.Exec(`
-        INSERT INTO comments (post_id, user_id, content, created_at) 
-        VALUES (?, ?, ?, CURRENT_TIMESTAMP)`,
-		comment.PostID, comment.UserID, comment.Content,
+	result, err := repo.DB.Exec(`
+        INSERT INTO comments (post_id, group_post_id, parent_id, depth, user_id, content, created_at) 
+        VALUES (NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0), ?, ?, ?, CURRENT_TIMESTAMP)`,
+		comment.PostID, comment.GroupPostID, comment.ParentID, comment.Depth, comment.UserID, comment.Content,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	comment.ID = int(id)
+	return nil
 }
 
+// DeleteComment deletes one of the user's comments together with every reply beneath it, along
+// with the likes on those comments and the notifications about them (likes and replies)
 func (repo *CommentRepository) DeleteComment(commentID, userID int) error {
-	_, err := repo.DB.Exec(`DELETE FROM comments WHERE id = ? AND user_id = ?`, commentID, userID)
-	return err
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const thread = `WITH RECURSIVE thread(id) AS (
+            SELECT id FROM comments WHERE id = ? AND user_id = ?
+            UNION ALL
+            SELECT c.id FROM comments c JOIN thread t ON c.parent_id = t.id)`
+	for _, query := range []string{
+		`DELETE FROM notification_actors WHERE notification_id IN (
+            SELECT id FROM notifications WHERE entity_type = 'comment' AND entity_id IN (SELECT id FROM thread))`,
+		`DELETE FROM notifications WHERE entity_type = 'comment' AND entity_id IN (SELECT id FROM thread)`,
+		`DELETE FROM likes WHERE comment_id IN (SELECT id FROM thread)`,
+		`DELETE FROM comments WHERE id IN (SELECT id FROM thread)`,
+	} {
+		if _, err := tx.Exec(thread+" "+query, commentID, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCommentsForPost retrieves a page of the top-level comments on a post, each with a preview
+// of its replies, as seen by the viewer
+func (repo *CommentRepository) GetCommentsForPost(postID, viewerID int, page CommentPage) ([]models.Comment, error) {
+	return repo.getTopLevelComments("c.post_id", postID, viewerID, page)
 }
 
-func (repo *CommentRepository) GetCommentsForPost(postID int) ([]models.Comment, error) {
+// GetCommentsForGroupPost retrieves a page of the top-level comments on a group post, each with
+// a preview of its replies, as seen by the viewer
+func (repo *CommentRepository) GetCommentsForGroupPost(groupPostID, viewerID int, page CommentPage) ([]models.Comment, error) {
+	return repo.getTopLevelComments("c.group_post_id", groupPostID, viewerID, page)
+}
+
+// getTopLevelComments pages through the comments that aren't replies, where column (a constant,
+// never user input) matches id. IDs grow with creation time, so they double as the cursor.
+func (repo *CommentRepository) getTopLevelComments(column string, id, viewerID int, page CommentPage) ([]models.Comment, error) {
+	cursor, order := "c.id > ?", "ASC"
+	if page.NewestFirst {
+		cursor, order = "c.id < ?", "DESC"
+		if page.AfterID == 0 {
+			page.AfterID = math.MaxInt32
+		}
+	}
+	return repo.queryThreads(viewerID, `
+        SELECT `+commentColumns+`
+        FROM comments c`+commentAuthor+`
+        WHERE `+column+` = ? AND c.parent_id IS NULL AND `+cursor+`
+        ORDER BY c.id `+order+` LIMIT ?`, id, page.AfterID, page.Limit)
+}
+
+// GetReplies pages through a comment's direct replies, oldest first, starting after afterID.
+// Each reply comes with a preview of its own replies.
+func (repo *CommentRepository) GetReplies(parentID, viewerID, afterID, limit int) ([]models.Comment, error) {
+	return repo.queryThreads(viewerID, `
+        SELECT `+commentColumns+`
+        FROM comments c`+commentAuthor+`
+        WHERE c.parent_id = ? AND c.id > ?
+        ORDER BY c.id ASC LIMIT ?`, parentID, afterID, limit)
+}
+
+// GetCommentByID retrieves a single comment; ViewerLiked is always false
+func (repo *CommentRepository) GetCommentByID(commentID int) (*models.Comment, error) {
+	return scanComment(repo.DB.QueryRow(`
+        SELECT `+commentColumns+`
+        FROM comments c`+commentAuthor+`WHERE c.id = ?`, 0, commentID))
+}
+
+// queryComments runs a query selecting commentColumns, passing the viewer's ID before args
+func (repo *CommentRepository) queryComments(viewerID int, query string, args ...interface{}) ([]models.Comment, error) {
 	var comments []models.Comment
 
-	rows, err := repo.DB.Query(`
-        SELECT id, post_id, user_id, content, created_at
-        FROM comments
-        WHERE post_id = ?
-        ORDER BY created_at ASC`, postID)
+	rows, err := repo.DB.Query(query, append([]interface{}{viewerID}, args...)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var comment models.Comment
-		err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt)
+		comment, err := scanComment(rows)
 		if err != nil {
 			return nil, err
 		}
-		comments = append(comments, comment)
+		comments = append(comments, *comment)
+	}
+	return comments, rows.Err()
+}
+
+// queryThreads runs a comment query and embeds the reply previews beneath every comment
+func (repo *CommentRepository) queryThreads(viewerID int, query string, args ...interface{}) ([]models.Comment, error) {
+	comments, err := repo.queryComments(viewerID, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return comments, repo.attachReplies(comments, viewerID)
+}
+
+// attachReplies fills in Replies with the first CommentReplyPreview replies of each comment,
+// one nesting level per query, down to MaxCommentDepth
+func (repo *CommentRepository) attachReplies(comments []models.Comment, viewerID int) error {
+	level := make([]*models.Comment, len(comments))
+	for i := range comments {
+		level[i] = &comments[i]
+	}
+
+	for len(level) > 0 {
+		parents := make(map[int]*models.Comment)
+		var placeholders []string
+		var args []interface{}
+		for _, comment := range level {
+			if comment.ReplyCount > 0 {
+				parents[comment.ID] = comment
+				placeholders = append(placeholders, "?")
+				args = append(args, comment.ID)
+			}
+		}
+		if len(parents) == 0 {
+			return nil
+		}
+
+		args = append(args, models.CommentReplyPreview)
+		replies, err := repo.queryComments(viewerID, `
+            SELECT `+commentColumns+`
+            FROM (SELECT *, ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY id) AS position
+                FROM comments WHERE parent_id IN (`+strings.Join(placeholders, ", ")+`)) c`+commentAuthor+`
+            WHERE c.position <= ?
+            ORDER BY c.id ASC`, args...)
+		if err != nil {
+			return err
+		}
+
+		for _, reply := range replies {
+			parent := parents[reply.ParentID]
+			parent.Replies = append(parent.Replies, reply)
+		}
+		// Collect the next level only once every parent's slice is final
+		level = level[:0]
+		for _, parent := range parents {
+			for i := range parent.Replies {
+				level = append(level, &parent.Replies[i])
+			}
+		}
 	}
-	return comments, nil
+	return nil
 }
 func (repo *CommentRepository) EditComment(commentID, userID int, content string) error {
 	_, err := repo.DB.Exec(`
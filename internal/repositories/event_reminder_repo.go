@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"social-network/internal/models"
+)
+
+// EventReminderRepository finds events that need reminders and records the ones already sent
+type EventReminderRepository struct {
+	DB *sql.DB
+}
+
+// NewEventReminderRepository creates a new instance of EventReminderRepository
+func NewEventReminderRepository(db *sql.DB) *EventReminderRepository {
+	return &EventReminderRepository{DB: db}
+}
+
+// occurrenceSlack widens the search for occurrences, which may have been moved away from their scheduled date
+const occurrenceSlack = "7 days"
+
+// GetReminderCandidates returns every going/maybe RSVP, from users still in the group, to a
+// non-cancelled event or occurrence that starts between from and to
+func (repo *EventReminderRepository) GetReminderCandidates(from, to time.Time) ([]models.EventReminderCandidate, error) {
+	rows, err := repo.DB.Query(`
+        SELECT r.event_id, r.occurrence_date, r.user_id
+        FROM event_rsvps r
+        JOIN group_events e ON r.event_id = e.id
+        JOIN group_members gm ON gm.group_id = e.group_id AND gm.user_id = r.user_id
+        WHERE r.status IN ('going', 'maybe') AND e.cancelled_at IS NULL AND gm.status != 'pending'
+          AND (
+            (r.occurrence_date = '' AND datetime(e.event_date) BETWEEN datetime(?1) AND datetime(?2))
+            OR (r.occurrence_date != '' AND datetime(r.occurrence_date)
+                BETWEEN datetime(?1, '-`+occurrenceSlack+`') AND datetime(?2, '+`+occurrenceSlack+`'))
+          )`, models.OccurrenceKey(from), models.OccurrenceKey(to))
+	if err != nil {
+		return nil, err
+	}
+	type response struct {
+		eventID        int
+		occurrenceDate string
+		userID         int
+	}
+	var responses []response
+	for rows.Next() {
+		var resp response
+		if err := rows.Scan(&resp.eventID, &resp.occurrenceDate, &resp.userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	eventRepo := NewGroupEventRepository(repo.DB)
+	events := make(map[int]*models.GroupEvent)
+	eventOverrides := make(map[int]map[string]models.EventOccurrenceOverride)
+	var candidates []models.EventReminderCandidate
+	for _, resp := range responses {
+		event, ok := events[resp.eventID]
+		if !ok {
+			if event, err = eventRepo.GetEventByID(resp.eventID); err != nil {
+				return nil, err
+			}
+			events[resp.eventID] = event
+			if event.Recurrence != nil {
+				if eventOverrides[event.ID], err = eventRepo.GetOccurrenceOverrides(event.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		view := *event
+		if resp.occurrenceDate != "" {
+			scheduled, err := models.ParseEventDate(resp.occurrenceDate)
+			if err != nil {
+				continue
+			}
+			occurrences, err := expandEvent(event, eventOverrides[event.ID], scheduled, scheduled)
+			if err != nil {
+				return nil, err
+			}
+			if len(occurrences) == 0 {
+				continue // Cancelled or no longer part of the series
+			}
+			view = occurrences[0]
+		}
+
+		start, err := view.StartTime()
+		if err != nil || start.Before(from) || start.After(to) {
+			continue
+		}
+		candidates = append(candidates, models.EventReminderCandidate{UserID: resp.userID, Event: view})
+	}
+	return candidates, nil
+}
+
+// ClaimReminders records reminders as sent and returns how many of them were new. Claiming
+// before sending means a crash can lose a reminder but never send it twice.
+func (repo *EventReminderRepository) ClaimReminders(eventID int, occurrenceDate string, userID int, offsets []int) (int, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	claimed := 0
+	for _, offset := range offsets {
+		result, err := tx.Exec(`
+            INSERT OR IGNORE INTO event_reminders_sent (event_id, occurrence_date, user_id, offset_minutes)
+            VALUES (?, ?, ?, ?)`, eventID, occurrenceDate, userID, offset)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		claimed += int(affected)
+	}
+	return claimed, tx.Commit()
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"database/sql"
 	"log"
+
+	"social-network/internal/models"
 )
 
 // EventRSVPRepository handles event RSVP operations
@@ -15,25 +17,241 @@ func NewEventRSVPRepository(db *sql.DB) *EventRSVPRepository {
 	return &EventRSVPRepository{DB: db}
 }
 
-// RSVPToEvent lets users mark themselves as "going" or "not going"
-func (repo *EventRSVPRepository) RSVPToEvent(eventID, userID int, status string) error {
-	_, err := repo.DB.Exec(`
-        INSERT INTO event_rsvps (event_id, user_id, status)
-        VALUES (?, ?, ?)
-        ON CONFLICT(event_id, user_id) DO UPDATE SET status = excluded.status`,
-		eventID, userID, status)
+// RSVPToEvent records a user's response to an event, or to one occurrence of a recurring event
+// (occurrenceDate is empty for one-off events). A "going" response on a full event is stored as
+// waitlisted; when a going user changes their answer the freed spot goes to the longest-waiting
+// user. It returns the stored status and the IDs of any promoted users.
+func (repo *EventRSVPRepository) RSVPToEvent(eventID int, occurrenceDate string, userID int, status string) (string, []int, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow(`SELECT status FROM event_rsvps WHERE event_id = ? AND occurrence_date = ? AND user_id = ?`,
+		eventID, occurrenceDate, userID).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return "", nil, err
+	}
+
+	// Users already holding a spot keep it; users already in the queue keep their place
+	if status == models.RSVPStatusGoing && previous == models.RSVPStatusWaitlisted {
+		status = models.RSVPStatusWaitlisted
+	} else if status == models.RSVPStatusGoing && previous != models.RSVPStatusGoing {
+		free, err := freeSpots(tx, eventID, occurrenceDate)
+		if err != nil {
+			return "", nil, err
+		}
+		if free == 0 {
+			status = models.RSVPStatusWaitlisted
+		}
+	}
+
+	if status != previous {
+		_, err = tx.Exec(`
+            INSERT INTO event_rsvps (event_id, occurrence_date, user_id, status)
+            VALUES (?, ?, ?, ?)
+            ON CONFLICT(event_id, user_id, occurrence_date) DO UPDATE SET status = excluded.status, updated_at = CURRENT_TIMESTAMP`,
+			eventID, occurrenceDate, userID, status)
+		if err != nil {
+			log.Println("❌ Failed to RSVP:", err)
+			return "", nil, err
+		}
+	}
+
+	var promoted []int
+	if previous == models.RSVPStatusGoing && status != models.RSVPStatusGoing {
+		promoted, err = promoteFromWaitlist(tx, eventID, occurrenceDate)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return status, promoted, tx.Commit()
+}
+
+// FillFromWaitlist promotes waitlisted users into any free spots of every occurrence,
+// e.g. after the capacity was raised
+func (repo *EventRSVPRepository) FillFromWaitlist(eventID int) ([]int, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+        SELECT DISTINCT occurrence_date FROM event_rsvps WHERE event_id = ? AND status = 'waitlisted'`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	var occurrenceDates []string
+	for rows.Next() {
+		var occurrenceDate string
+		if err := rows.Scan(&occurrenceDate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		occurrenceDates = append(occurrenceDates, occurrenceDate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var promoted []int
+	for _, occurrenceDate := range occurrenceDates {
+		userIDs, err := promoteFromWaitlist(tx, eventID, occurrenceDate)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, userIDs...)
+	}
+	return promoted, tx.Commit()
+}
+
+// freeSpots returns how many more users can go to an event occurrence, or -1 if it has no capacity limit
+func freeSpots(q queryer, eventID int, occurrenceDate string) (int, error) {
+	var capacity sql.NullInt64
+	var going int
+	err := q.QueryRow(`
+        SELECT capacity, (SELECT COUNT(*) FROM event_rsvps WHERE event_id = ?1 AND occurrence_date = ?2 AND status = 'going')
+        FROM group_events WHERE id = ?1`, eventID, occurrenceDate).Scan(&capacity, &going)
+	if err != nil {
+		return 0, err
+	}
+	if !capacity.Valid {
+		return -1, nil
+	}
+	if going >= int(capacity.Int64) {
+		return 0, nil
+	}
+	return int(capacity.Int64) - going, nil
+}
+
+// promoteFromWaitlist moves waitlisted users to going, oldest first, until the occurrence is full
+func promoteFromWaitlist(tx *sql.Tx, eventID int, occurrenceDate string) ([]int, error) {
+	free, err := freeSpots(tx, eventID, occurrenceDate)
+	if err != nil {
+		return nil, err
+	}
+	if free == 0 {
+		return nil, nil
+	}
 
+	query := `SELECT user_id FROM event_rsvps WHERE event_id = ? AND occurrence_date = ? AND status = 'waitlisted'
+        ORDER BY updated_at ASC, id ASC`
+	args := []interface{}{eventID, occurrenceDate}
+	if free > 0 {
+		query += ` LIMIT ?`
+		args = append(args, free)
+	}
+	rows, err := tx.Query(query, args...)
 	if err != nil {
-		log.Println("❌ Failed to RSVP:", err)
+		return nil, err
+	}
+	var promoted []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		promoted = append(promoted, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return err
+
+	for _, userID := range promoted {
+		_, err := tx.Exec(`
+            UPDATE event_rsvps SET status = 'going', updated_at = CURRENT_TIMESTAMP
+            WHERE event_id = ? AND occurrence_date = ? AND user_id = ?`, eventID, occurrenceDate, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
 }
 
-// GetRSVPCount returns the number of users who are "going" to an event
-func (repo *EventRSVPRepository) GetRSVPCount(eventID int) (int, error) {
+// GetRSVPCount returns the number of users who are "going" to an event occurrence
+func (repo *EventRSVPRepository) GetRSVPCount(eventID int, occurrenceDate string) (int, error) {
 	var count int
 	err := repo.DB.QueryRow(`
-        SELECT COUNT(*) FROM event_rsvps WHERE event_id = ? AND status = 'going'`,
-		eventID).Scan(&count)
+        SELECT COUNT(*) FROM event_rsvps WHERE event_id = ? AND occurrence_date = ? AND status = 'going'`,
+		eventID, occurrenceDate).Scan(&count)
 	return count, err
 }
+
+// GetRSVPBreakdown counts an occurrence's responses per status; every status is present, even at zero
+func (repo *EventRSVPRepository) GetRSVPBreakdown(eventID int, occurrenceDate string) (map[string]int, error) {
+	rows, err := repo.DB.Query(`
+        SELECT status, COUNT(*) FROM event_rsvps WHERE event_id = ? AND occurrence_date = ? GROUP BY status`,
+		eventID, occurrenceDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(models.RSVPStatuses))
+	for _, status := range models.RSVPStatuses {
+		counts[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetAttendees returns the users who responded to an event occurrence, grouped by RSVP status
+func (repo *EventRSVPRepository) GetAttendees(eventID int, occurrenceDate string) (map[string][]models.EventAttendee, error) {
+	rows, err := repo.DB.Query(`
+        SELECT r.status, r.user_id, u.nickname
+        FROM event_rsvps r
+        JOIN users u ON r.user_id = u.id
+        WHERE r.event_id = ? AND r.occurrence_date = ?
+        ORDER BY r.updated_at ASC, r.id ASC`, eventID, occurrenceDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attendees := make(map[string][]models.EventAttendee)
+	for rows.Next() {
+		var status string
+		var attendee models.EventAttendee
+		if err := rows.Scan(&status, &attendee.UserID, &attendee.Nickname); err != nil {
+			return nil, err
+		}
+		attendees[status] = append(attendees[status], attendee)
+	}
+	return attendees, rows.Err()
+}
+
+// GetRespondentIDs returns every user who has RSVP'd to an event, whatever their answer.
+// An empty occurrenceDate covers every occurrence of a recurring event.
+func (repo *EventRSVPRepository) GetRespondentIDs(eventID int, occurrenceDate string) ([]int, error) {
+	rows, err := repo.DB.Query(`
+        SELECT DISTINCT user_id FROM event_rsvps
+        WHERE event_id = ?1 AND (?2 = '' OR occurrence_date = ?2)`, eventID, occurrenceDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
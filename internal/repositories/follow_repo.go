@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"social-network/internal/models"
+)
+
+// ErrNoPendingFollow is returned when answering a follow request that doesn't exist
+var ErrNoPendingFollow = errors.New("no pending follow request")
+
+// FollowRepository handles database operations for followers
+type FollowRepository struct {
+	DB *sql.DB
+}
+
+// NewFollowRepository creates a new instance of FollowRepository
+func NewFollowRepository(db *sql.DB) *FollowRepository {
+	return &FollowRepository{DB: db}
+}
+
+// GetFollow returns the follow relationship between two users, or sql.ErrNoRows if there is none
+func (repo *FollowRepository) GetFollow(followerID, followingID int) (*models.Follow, error) {
+	var follow models.Follow
+	err := repo.DB.QueryRow(`
+		SELECT id, follower_id, following_id, status FROM followers
+		WHERE follower_id = ? AND following_id = ?`, followerID, followingID).
+		Scan(&follow.ID, &follow.FollowerID, &follow.FollowingID, &follow.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+// RequestFollow creates a pending follow request. If the users already have a relationship it is
+// returned unchanged and created is false.
+func (repo *FollowRepository) RequestFollow(followerID, followingID int) (follow *models.Follow, created bool, err error) {
+	result, err := repo.DB.Exec(`
+		INSERT INTO followers (follower_id, following_id, status) VALUES (?, ?, 'pending')
+		ON CONFLICT (follower_id, following_id) DO NOTHING`, followerID, followingID)
+	if err != nil {
+		return nil, false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if inserted == 0 {
+		follow, err = repo.GetFollow(followerID, followingID)
+		return follow, false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, false, err
+	}
+	return &models.Follow{ID: int(id), FollowerID: followerID, FollowingID: followingID, Status: "pending"}, true, nil
+}
+
+// AcceptFollowRequest accepts a pending request to follow the given user and returns it
+func (repo *FollowRepository) AcceptFollowRequest(followID, followingID int) (*models.Follow, error) {
+	follow, err := repo.getPendingFollow(followID, followingID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repo.DB.Exec(`UPDATE followers SET status = 'accepted' WHERE id = ?`, followID); err != nil {
+		return nil, err
+	}
+	follow.Status = "accepted"
+	return follow, nil
+}
+
+// DeclineFollowRequest deletes a pending request to follow the given user and returns it
+func (repo *FollowRepository) DeclineFollowRequest(followID, followingID int) (*models.Follow, error) {
+	follow, err := repo.getPendingFollow(followID, followingID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repo.DB.Exec(`DELETE FROM followers WHERE id = ?`, followID); err != nil {
+		return nil, err
+	}
+	return follow, nil
+}
+
+func (repo *FollowRepository) getPendingFollow(followID, followingID int) (*models.Follow, error) {
+	var follow models.Follow
+	err := repo.DB.QueryRow(`
+		SELECT id, follower_id, following_id, status FROM followers
+		WHERE id = ? AND following_id = ? AND status = 'pending'`, followID, followingID).
+		Scan(&follow.ID, &follow.FollowerID, &follow.FollowingID, &follow.Status)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoPendingFollow
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"social-network/internal/models"
+)
+
+// ErrUserBanned is returned when a banned user tries to join a group
+var ErrUserBanned = errors.New("you are banned from this group")
+
+// Permission errors returned when removing, banning and unbanning users
+var (
+	ErrCannotModerateSelf  = errors.New("you can't remove or ban yourself")
+	ErrCannotRemoveMembers = errors.New("you don't have permission to remove members")
+	ErrMemberOutranked     = errors.New("you can only remove members below your own role")
+	ErrCannotManageBans    = errors.New("you don't have permission to manage bans")
+)
+
+// checkCanModerate ensures the actor may remove the target: the actor needs the
+// remove_members permission and must outrank the target's current role
+func (repo *GroupRepository) checkCanModerate(groupID, userID, actorID int) error {
+	if userID == actorID {
+		return ErrCannotModerateSelf
+	}
+
+	actorRole, err := repo.GetMemberRole(actorID, groupID)
+	if err != nil {
+		return err
+	}
+	if !models.GroupRoleHasPermission(actorRole, models.GroupPermRemoveMembers) {
+		return ErrCannotRemoveMembers
+	}
+
+	targetRole, err := repo.GetMemberRole(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if models.GroupRoleRank(targetRole) >= models.GroupRoleRank(actorRole) {
+		return ErrMemberOutranked
+	}
+	return nil
+}
+
+// RemoveMember kicks a member (or pending requester) out of a group
+func (repo *GroupRepository) RemoveMember(groupID, userID, actorID int) error {
+	if err := repo.checkCanModerate(groupID, userID, actorID); err != nil {
+		return err
+	}
+
+	result, err := repo.DB.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+// BanMember removes a user from a group and prevents them from rejoining until the ban expires.
+// Users who aren't members can be banned too; unknown users return sql.ErrNoRows.
+func (repo *GroupRepository) BanMember(groupID, userID, actorID int, reason string, expiresAt *time.Time) error {
+	if err := repo.checkCanModerate(groupID, userID, actorID); err != nil {
+		return err
+	}
+	var exists bool
+	if err := repo.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        INSERT INTO group_bans (group_id, user_id, banned_by, reason, expires_at)
+        VALUES (?, ?, ?, NULLIF(?, ''), ?)
+        ON CONFLICT(group_id, user_id) DO UPDATE SET
+            banned_by = excluded.banned_by, reason = excluded.reason,
+            expires_at = excluded.expires_at, created_at = CURRENT_TIMESTAMP`,
+		groupID, userID, actorID, reason, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ?", groupID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("🚫 User %d banned from Group %d by User %d", userID, groupID, actorID)
+	return nil
+}
+
+// UnbanMember lifts a ban
+func (repo *GroupRepository) UnbanMember(groupID, userID, actorID int) error {
+	allowed, err := repo.HasGroupPermission(actorID, groupID, models.GroupPermRemoveMembers)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrCannotManageBans
+	}
+
+	result, err := repo.DB.Exec("DELETE FROM group_bans WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IsUserBanned checks if a user has an active (permanent or unexpired) ban in a group
+func (repo *GroupRepository) IsUserBanned(userID, groupID int) (bool, error) {
+	return isUserBanned(repo.DB, userID, groupID)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func isUserBanned(db queryer, userID, groupID int) (bool, error) {
+	var banned bool
+	err := db.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM group_bans
+        WHERE group_id = ? AND user_id = ? AND (expires_at IS NULL OR expires_at > ?))`,
+		groupID, userID, time.Now().UTC()).Scan(&banned)
+	return banned, err
+}
+
+// GetGroupBans lists a group's active bans, newest first
+func (repo *GroupRepository) GetGroupBans(groupID int) ([]models.GroupBan, error) {
+	rows, err := repo.DB.Query(`
+        SELECT b.id, b.group_id, b.user_id, u.nickname, b.banned_by, COALESCE(b.reason, ''), b.expires_at, b.created_at
+        FROM group_bans b
+        JOIN users u ON b.user_id = u.id
+        WHERE b.group_id = ? AND (b.expires_at IS NULL OR b.expires_at > ?)
+        ORDER BY b.created_at DESC`, groupID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []models.GroupBan
+	for rows.Next() {
+		var ban models.GroupBan
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&ban.ID, &ban.GroupID, &ban.UserID, &ban.Nickname, &ban.BannedBy,
+			&ban.Reason, &expiresAt, &ban.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			ban.ExpiresAt = &expiresAt.Time
+		}
+		bans = append(bans, ban)
+	}
+	return bans, rows.Err()
+}
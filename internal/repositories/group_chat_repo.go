@@ -56,3 +56,22 @@ func (repo *GroupChatRepository) GetGroupMessages(groupID int) ([]models.GroupCh
 
 	return messages, nil
 }
+
+// GetGroupChatMessage fetches a single chat message by ID
+func (repo *GroupChatRepository) GetGroupChatMessage(messageID int) (*models.GroupChatMessage, error) {
+	var msg models.GroupChatMessage
+	err := repo.DB.QueryRow(`
+		SELECT id, group_id, sender_id, content, sent_at
+		FROM group_chat_messages WHERE id = ?`, messageID).
+		Scan(&msg.ID, &msg.GroupID, &msg.SenderID, &msg.Content, &msg.SentAt)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// DeleteGroupChatMessage removes a chat message
+func (repo *GroupChatRepository) DeleteGroupChatMessage(messageID int) error {
+	_, err := repo.DB.Exec(`DELETE FROM group_chat_messages WHERE id = ?`, messageID)
+	return err
+}
@@ -4,6 +4,15 @@ import (
 	"database/sql"
 	"log"
 	"social-network/internal/models"
+	"sort"
+	"time"
+)
+
+const (
+	// recurrenceHorizon is how far ahead recurring events are expanded in listings
+	recurrenceHorizon = 180 * 24 * time.Hour
+	// maxListedOccurrences caps how many occurrences of one series a listing returns
+	maxListedOccurrences = 50
 )
 
 // GroupEventRepository handles group event-related operations
@@ -16,42 +25,530 @@ func NewGroupEventRepository(db *sql.DB) *GroupEventRepository {
 	return &GroupEventRepository{DB: db}
 }
 
-// ✅ CreateGroupEvent adds a new event to a group
+// ✅ CreateGroupEvent adds a new event to a group and fills in its ID
 func (repo *GroupEventRepository) CreateGroupEvent(event *models.GroupEvent) error {
-	_, err := repo.DB.Exec(`
-        INSERT INTO group_events (group_id, creator_id, title, description, event_date)
-        VALUES (?, ?, ?, ?, ?)`,
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-		event.GroupID, event.CreatorID, event.Title, event.Description, event.EventDate)
-	return err
+	freq, interval, until, count := recurrenceColumns(event.Recurrence)
+	result, err := tx.Exec(`
+        INSERT INTO group_events (group_id, creator_id, title, description, event_date, capacity,
+            recurrence_freq, recurrence_interval, recurrence_until, recurrence_count)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+
+		event.GroupID, event.CreatorID, event.Title, event.Description, event.EventDate, event.Capacity,
+		freq, interval, until, count)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if event.Recurrence != nil {
+		if err := insertExceptions(tx, int(id), event.Recurrence.Exceptions); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	event.ID = int(id)
+	return nil
 }
 
-// ✅ GetEventByID retrieves an event's details by ID
-func (repo *GroupEventRepository) GetEventByID(eventID int) (*models.GroupEvent, error) {
+// recurrenceColumns converts a rule to its column values; all NULL for one-off events
+func recurrenceColumns(r *models.EventRecurrence) (freq interface{}, interval int, until interface{}, count interface{}) {
+	if r == nil {
+		return nil, 1, nil, nil
+	}
+	freq = r.Freq
+	if r.Until != "" {
+		until = r.Until
+	}
+	if r.Count > 0 {
+		count = r.Count
+	}
+	return freq, r.Interval, until, count
+}
+
+// insertExceptions marks occurrences of a series as skipped
+func insertExceptions(tx *sql.Tx, eventID int, dates []string) error {
+	for _, date := range dates {
+		_, err := tx.Exec(`
+            INSERT INTO group_event_occurrences (event_id, occurrence_date, excluded) VALUES (?, ?, 1)
+            ON CONFLICT(event_id, occurrence_date) DO UPDATE SET excluded = 1, updated_at = CURRENT_TIMESTAMP`,
+			eventID, date)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupEventColumns lists the columns scanned by scanGroupEvent
+const groupEventColumns = `id, group_id, creator_id, title, description, event_date, capacity, sequence, created_at, updated_at, cancelled_at,
+    recurrence_freq, recurrence_interval, recurrence_until, recurrence_count`
+
+func scanGroupEvent(row rowScanner) (*models.GroupEvent, error) {
 	var event models.GroupEvent
-	err := repo.DB.QueryRow(`
-		SELECT id, group_id, creator_id, title, description, event_date 
-		FROM group_events WHERE id = ?`, eventID).
-		Scan(&event.ID, &event.GroupID, &event.CreatorID, &event.Title, &event.Description, &event.EventDate)
+	var capacity, recurrenceCount sql.NullInt64
+	var updatedAt, cancelledAt sql.NullTime
+	var recurrenceFreq, recurrenceUntil sql.NullString
+	var recurrenceInterval int
+	err := row.Scan(&event.ID, &event.GroupID, &event.CreatorID, &event.Title, &event.Description,
+		&event.EventDate, &capacity, &event.Sequence, &event.CreatedAt, &updatedAt, &cancelledAt,
+		&recurrenceFreq, &recurrenceInterval, &recurrenceUntil, &recurrenceCount)
+	if err != nil {
+		return nil, err
+	}
+	if capacity.Valid {
+		limit := int(capacity.Int64)
+		event.Capacity = &limit
+	}
+	if updatedAt.Valid {
+		event.UpdatedAt = &updatedAt.Time
+	}
+	if cancelledAt.Valid {
+		event.CancelledAt = &cancelledAt.Time
+	}
+	if recurrenceFreq.Valid {
+		event.Recurrence = &models.EventRecurrence{
+			Freq:     recurrenceFreq.String,
+			Interval: recurrenceInterval,
+			Until:    recurrenceUntil.String,
+			Count:    int(recurrenceCount.Int64),
+		}
+	}
+	return &event, nil
+}
+
+// ✅ GetEventByID retrieves an event's details by ID, including the exceptions of a recurring event
+func (repo *GroupEventRepository) GetEventByID(eventID int) (*models.GroupEvent, error) {
+	event, err := scanGroupEvent(repo.DB.QueryRow(`
+		SELECT `+groupEventColumns+` 
+		FROM group_events WHERE id = ?`, eventID))
 
 	if err != nil {
 		log.Println("❌ Error fetching event:", err)
 		return nil, err
 	}
-	return &event, nil
+	if event.Recurrence != nil {
+		overrides, err := repo.GetOccurrenceOverrides(eventID)
+		if err != nil {
+			return nil, err
+		}
+		event.Recurrence.Exceptions = exceptionDates(overrides)
+	}
+	return event, nil
 }
 
-// ✅ RSVPToEvent allows users to RSVP for an event
-func (repo *GroupEventRepository) RSVPToEvent(eventID, userID int, status string) error {
-	_, err := repo.DB.Exec(`
-	INSERT INTO event_rsvps (event_id, user_id, status, created_at) 
-	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
-	ON CONFLICT(event_id, user_id) 
-	DO UPDATE SET status = excluded.status, created_at = CURRENT_TIMESTAMP`,
-		eventID, userID, status)
+// GetOccurrenceOverrides returns a recurring event's per-occurrence changes, keyed by occurrence date
+func (repo *GroupEventRepository) GetOccurrenceOverrides(eventID int) (map[string]models.EventOccurrenceOverride, error) {
+	overrides, err := repo.queryOverrides(`
+        SELECT event_id, occurrence_date, excluded, title, description, event_date
+        FROM group_event_occurrences WHERE event_id = ?`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return overrides[eventID], nil
+}
+
+// getGroupOverrides returns the per-occurrence changes of all of a group's recurring events in one
+// query, keyed by event ID and then occurrence date
+func (repo *GroupEventRepository) getGroupOverrides(groupID int) (map[int]map[string]models.EventOccurrenceOverride, error) {
+	return repo.queryOverrides(`
+        SELECT o.event_id, o.occurrence_date, o.excluded, o.title, o.description, o.event_date
+        FROM group_event_occurrences o
+        JOIN group_events e ON o.event_id = e.id
+        WHERE e.group_id = ? AND e.recurrence_freq IS NOT NULL`, groupID)
+}
 
+// queryOverrides reads per-occurrence changes, grouped by event ID and keyed by occurrence date
+func (repo *GroupEventRepository) queryOverrides(query string, args ...interface{}) (map[int]map[string]models.EventOccurrenceOverride, error) {
+	rows, err := repo.DB.Query(query, args...)
 	if err != nil {
-		log.Println("❌ Error updating RSVP:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[int]map[string]models.EventOccurrenceOverride)
+	for rows.Next() {
+		var eventID int
+		var o models.EventOccurrenceOverride
+		var title, description, eventDate sql.NullString
+		if err := rows.Scan(&eventID, &o.OccurrenceDate, &o.Excluded, &title, &description, &eventDate); err != nil {
+			return nil, err
+		}
+		if title.Valid {
+			o.Title = &title.String
+		}
+		if description.Valid {
+			o.Description = &description.String
+		}
+		if eventDate.Valid {
+			o.EventDate = &eventDate.String
+		}
+		if overrides[eventID] == nil {
+			overrides[eventID] = make(map[string]models.EventOccurrenceOverride)
+		}
+		overrides[eventID][o.OccurrenceDate] = o
 	}
+	return overrides, rows.Err()
+}
+
+// GetOccurrenceKeys lists the distinct occurrence keys an event's RSVPs and occurrence changes are
+// stored under ("" for one-off events). Plain exceptions are only included with withExceptions,
+// for edits that keep the stored exception set.
+func (repo *GroupEventRepository) GetOccurrenceKeys(eventID int, withExceptions bool) ([]string, error) {
+	rows, err := repo.DB.Query(`
+        SELECT occurrence_date FROM event_rsvps WHERE event_id = ?1
+        UNION
+        SELECT occurrence_date FROM group_event_occurrences WHERE event_id = ?1
+            AND (?2 OR excluded = 0 OR title IS NOT NULL OR description IS NOT NULL OR event_date IS NOT NULL)`,
+		eventID, withExceptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// exceptionDates lists the excluded occurrences, earliest first
+func exceptionDates(overrides map[string]models.EventOccurrenceOverride) []string {
+	var dates []string
+	for date, o := range overrides {
+		if o.Excluded {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// expandEvent turns a recurring event into its occurrences between from and to, applying its
+// per-occurrence changes and leaving out excluded ones; one-off events are returned unchanged
+func expandEvent(event *models.GroupEvent, overrides map[string]models.EventOccurrenceOverride, from, to time.Time) ([]models.GroupEvent, error) {
+	if event.Recurrence == nil {
+		return []models.GroupEvent{*event}, nil
+	}
+	event.Recurrence.Exceptions = exceptionDates(overrides)
+
+	starts, err := event.Occurrences(from, to)
+	if err != nil {
+		return nil, err
+	}
+	var occurrences []models.GroupEvent
+	for _, start := range starts {
+		override, ok := overrides[models.OccurrenceKey(start)]
+		if ok && override.Excluded {
+			continue
+		}
+		if ok {
+			occurrences = append(occurrences, event.AtOccurrence(start, &override))
+		} else {
+			occurrences = append(occurrences, event.AtOccurrence(start, nil))
+		}
+	}
+	return occurrences, nil
+}
+
+// GetGroupEvents lists a group's events, with recurring events expanded into their occurrences.
+// "upcoming" returns future events soonest first, "past" returns finished events most recent
+// first, anything else returns all events by date.
+func (repo *GroupEventRepository) GetGroupEvents(groupID int, when string) ([]models.GroupEvent, error) {
+	query := `SELECT ` + groupEventColumns + ` FROM group_events WHERE group_id = ?`
+	switch when {
+	case "upcoming":
+		query += ` AND (recurrence_freq IS NOT NULL OR datetime(event_date) >= datetime('now'))`
+	case "past":
+		query += ` AND datetime(event_date) < datetime('now')`
+	}
+
+	rows, err := repo.DB.Query(query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	var series []*models.GroupEvent
+	for rows.Next() {
+		event, err := scanGroupEvent(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		series = append(series, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := repo.getGroupOverrides(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	from, to := time.Time{}, now.Add(recurrenceHorizon)
+	switch when {
+	case "upcoming":
+		from = now
+	case "past":
+		to = now.Add(-time.Nanosecond)
+	}
+
+	var events []models.GroupEvent
+	for _, event := range series {
+		occurrences, err := expandEvent(event, overrides[event.ID], from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(occurrences) > maxListedOccurrences {
+			if when == "past" {
+				occurrences = occurrences[len(occurrences)-maxListedOccurrences:]
+			} else {
+				occurrences = occurrences[:maxListedOccurrences]
+			}
+		}
+		events = append(events, occurrences...)
+	}
+
+	sortEventsByDate(events, when == "past")
+	return events, nil
+}
+
+// sortEventsByDate orders events by start time; unparseable dates sort first
+func sortEventsByDate(events []models.GroupEvent, descending bool) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, _ := events[i].StartTime()
+		b, _ := events[j].StartTime()
+		if descending {
+			return a.After(b)
+		}
+		return a.Before(b)
+	})
+}
+
+// UpdateGroupEvent saves a new title, description, date, capacity and recurrence for a whole event
+// series. With replaceExceptions the series' exceptions become exactly event.Recurrence.Exceptions,
+// restoring any cancelled occurrence left out; otherwise the stored ones are kept. Reminders already
+// sent are forgotten if the date changes, so attendees hear about the new time.
+func (repo *GroupEventRepository) UpdateGroupEvent(event *models.GroupEvent, replaceExceptions bool) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Sent reminders are keyed by occurrence, not start time, so forget them when the event moves
+	_, err = tx.Exec(`
+        DELETE FROM event_reminders_sent
+        WHERE event_id = ?1 AND (SELECT event_date FROM group_events WHERE id = ?1) != ?2`,
+		event.ID, event.EventDate)
+	if err != nil {
+		return err
+	}
+
+	freq, interval, until, count := recurrenceColumns(event.Recurrence)
+	_, err = tx.Exec(`
+        UPDATE group_events
+        SET title = ?, description = ?, event_date = ?, capacity = ?,
+            recurrence_freq = ?, recurrence_interval = ?, recurrence_until = ?, recurrence_count = ?,
+            sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?`,
+		event.Title, event.Description, event.EventDate, event.Capacity,
+		freq, interval, until, count, event.ID)
+	if err != nil {
+		return err
+	}
+	if replaceExceptions {
+		var exceptions []string
+		if event.Recurrence != nil {
+			exceptions = event.Recurrence.Exceptions
+		}
+		if err := replaceExceptionSet(tx, event.ID, exceptions); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// replaceExceptionSet makes dates the only skipped occurrences of a series. Occurrences that are
+// no longer skipped keep their other changes; rows left with no change at all are removed.
+func replaceExceptionSet(tx *sql.Tx, eventID int, dates []string) error {
+	_, err := tx.Exec(`
+        UPDATE group_event_occurrences SET excluded = 0, updated_at = CURRENT_TIMESTAMP
+        WHERE event_id = ? AND excluded = 1`, eventID)
+	if err != nil {
+		return err
+	}
+	if err := insertExceptions(tx, eventID, dates); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+        DELETE FROM group_event_occurrences
+        WHERE event_id = ? AND excluded = 0 AND title IS NULL AND description IS NULL AND event_date IS NULL`, eventID)
 	return err
 }
+
+// SaveOccurrenceOverride changes a single occurrence of a recurring event; nil fields keep their
+// current value. The series sequence is bumped so calendar clients pick up the change, and
+// reminders already sent for the occurrence are forgotten if it moves.
+func (repo *GroupEventRepository) SaveOccurrenceOverride(eventID int, override *models.EventOccurrenceOverride) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Let reminders go out again for an occurrence that moves to a new start time
+	if override.EventDate != nil {
+		_, err = tx.Exec(`
+            DELETE FROM event_reminders_sent
+            WHERE event_id = ?1 AND occurrence_date = ?2 AND ?3 != COALESCE(
+                (SELECT event_date FROM group_event_occurrences WHERE event_id = ?1 AND occurrence_date = ?2), ?2)`,
+			eventID, override.OccurrenceDate, *override.EventDate)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO group_event_occurrences (event_id, occurrence_date, title, description, event_date)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(event_id, occurrence_date) DO UPDATE SET
+            title = COALESCE(excluded.title, title),
+            description = COALESCE(excluded.description, description),
+            event_date = COALESCE(excluded.event_date, event_date),
+            updated_at = CURRENT_TIMESTAMP`,
+		eventID, override.OccurrenceDate, override.Title, override.Description, override.EventDate)
+	if err != nil {
+		return err
+	}
+	if err := bumpEventSequence(tx, eventID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExcludeOccurrence cancels a single occurrence of a recurring event
+func (repo *GroupEventRepository) ExcludeOccurrence(eventID int, occurrenceDate string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertExceptions(tx, eventID, []string{occurrenceDate}); err != nil {
+		return err
+	}
+	if err := bumpEventSequence(tx, eventID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func bumpEventSequence(tx *sql.Tx, eventID int) error {
+	_, err := tx.Exec(`
+        UPDATE group_events SET sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, eventID)
+	return err
+}
+
+// CancelGroupEvent marks an event as cancelled; it stays listed so attendees can see what happened
+func (repo *GroupEventRepository) CancelGroupEvent(eventID int) error {
+	_, err := repo.DB.Exec(`
+        UPDATE group_events
+        SET cancelled_at = CURRENT_TIMESTAMP, sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ? AND cancelled_at IS NULL`, eventID)
+	return err
+}
+
+// GetCalendarEvents returns the events (or, for recurring events, the occurrences) a user is going
+// to or might go to, in groups they still belong to. Cancelled occurrences they answered stay in
+// the list with CancelledAt set, so calendar apps mark them cancelled rather than losing them.
+func (repo *GroupEventRepository) GetCalendarEvents(userID int) ([]models.GroupEvent, error) {
+	rows, err := repo.DB.Query(`
+        SELECT r.event_id, r.occurrence_date FROM event_rsvps r
+        JOIN group_events e ON r.event_id = e.id
+        JOIN group_members gm ON gm.group_id = e.group_id AND gm.user_id = r.user_id
+        WHERE r.user_id = ? AND r.status IN ('going', 'maybe') AND gm.status != 'pending'`, userID)
+	if err != nil {
+		return nil, err
+	}
+	type response struct {
+		eventID        int
+		occurrenceDate string
+	}
+	var responses []response
+	for rows.Next() {
+		var resp response
+		if err := rows.Scan(&resp.eventID, &resp.occurrenceDate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	series := make(map[int]*models.GroupEvent)
+	seriesOverrides := make(map[int]map[string]models.EventOccurrenceOverride)
+	var events []models.GroupEvent
+	for _, resp := range responses {
+		event, ok := series[resp.eventID]
+		if !ok {
+			if event, err = repo.GetEventByID(resp.eventID); err != nil {
+				return nil, err
+			}
+			series[resp.eventID] = event
+			if event.Recurrence != nil {
+				if seriesOverrides[event.ID], err = repo.GetOccurrenceOverrides(event.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if event.Recurrence == nil {
+			events = append(events, *event)
+			continue
+		}
+		start, err := models.ParseEventDate(resp.occurrenceDate)
+		if err != nil {
+			continue // RSVP to an occurrence that no longer exists
+		}
+		occurrences, err := expandEvent(event, seriesOverrides[event.ID], start, start)
+		if err != nil {
+			return nil, err
+		}
+		override, ok := seriesOverrides[event.ID][models.OccurrenceKey(start)]
+		if ok && override.Excluded && event.IsScheduledAt(resp.occurrenceDate) {
+			// Excluding an occurrence bumped the series' sequence and update time
+			cancelled := event.AtOccurrence(start, &override)
+			cancelled.CancelledAt = event.UpdatedAt
+			if cancelled.CancelledAt == nil {
+				now := time.Now().UTC()
+				cancelled.CancelledAt = &now
+			}
+			occurrences = append(occurrences, cancelled)
+		}
+		events = append(events, occurrences...)
+	}
+
+	sortEventsByDate(events, false)
+	return events, nil
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"social-network/internal/models"
+)
+
+func TestExpandEvent(t *testing.T) {
+	moved := "2027-01-18T12:00:00Z"
+	title := "Special edition"
+	overrides := map[string]models.EventOccurrenceOverride{
+		"2027-01-11T10:00:00Z": {OccurrenceDate: "2027-01-11T10:00:00Z", Excluded: true},
+		"2027-01-18T10:00:00Z": {OccurrenceDate: "2027-01-18T10:00:00Z", Title: &title, EventDate: &moved},
+	}
+	event := &models.GroupEvent{
+		ID:         3,
+		Title:      "Weekly meetup",
+		EventDate:  "2027-01-04T10:00:00Z",
+		Recurrence: &models.EventRecurrence{Freq: models.RecurrenceWeekly, Interval: 1, Count: 4},
+	}
+
+	occurrences, err := expandEvent(event, overrides, time.Time{}, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("expandEvent() = %v", err)
+	}
+
+	type occurrence struct{ Key, Title, Start string }
+	var got []occurrence
+	for _, o := range occurrences {
+		got = append(got, occurrence{o.OccurrenceDate, o.Title, o.EventDate})
+	}
+	want := []occurrence{
+		{"2027-01-04T10:00:00Z", "Weekly meetup", "2027-01-04T10:00:00Z"},
+		{"2027-01-18T10:00:00Z", "Special edition", "2027-01-18T12:00:00Z"},
+		{"2027-01-25T10:00:00Z", "Weekly meetup", "2027-01-25T10:00:00Z"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandEvent() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(event.Recurrence.Exceptions, []string{"2027-01-11T10:00:00Z"}) {
+		t.Errorf("Exceptions = %v, want the excluded occurrence", event.Recurrence.Exceptions)
+	}
+}
+
+func TestExpandEventOneOff(t *testing.T) {
+	event := &models.GroupEvent{ID: 1, Title: "Launch", EventDate: "2027-01-04T10:00:00Z"}
+
+	occurrences, err := expandEvent(event, nil, time.Time{}, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("expandEvent() = %v", err)
+	}
+	if len(occurrences) != 1 || occurrences[0].OccurrenceDate != "" || occurrences[0].Title != "Launch" {
+		t.Errorf("expandEvent() = %+v, want the event unchanged", occurrences)
+	}
+}
@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"social-network/internal/models"
+)
+
+var (
+	// ErrInviteLinkUnavailable is returned when a token is unknown, revoked, expired or used up
+	ErrInviteLinkUnavailable = errors.New("invite link is invalid, expired or has reached its usage limit")
+	// ErrAlreadyGroupMember is returned when the invited user already belongs to the group
+	ErrAlreadyGroupMember = errors.New("you are already a member of this group")
+)
+
+// GroupInviteRepository handles shareable group invite links
+type GroupInviteRepository struct {
+	DB *sql.DB
+}
+
+// NewGroupInviteRepository creates a new instance of GroupInviteRepository
+func NewGroupInviteRepository(db *sql.DB) *GroupInviteRepository {
+	return &GroupInviteRepository{DB: db}
+}
+
+// generateToken returns a random URL-safe token
+func generateToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateInviteLink stores a new invite link for a group and fills in its ID and token
+func (repo *GroupInviteRepository) CreateInviteLink(link *models.GroupInviteLink) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	result, err := repo.DB.Exec(`
+        INSERT INTO group_invite_links (group_id, token, created_by, expires_at, max_uses)
+        VALUES (?, ?, ?, ?, ?)`,
+		link.GroupID, token, link.CreatedBy, link.ExpiresAt, link.MaxUses)
+	if err != nil {
+		log.Println("❌ Failed to create invite link:", err)
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	link.ID = int(id)
+	link.Token = token
+	link.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// GetGroupInviteLinks lists every invite link of a group, newest first
+func (repo *GroupInviteRepository) GetGroupInviteLinks(groupID int) ([]models.GroupInviteLink, error) {
+	rows, err := repo.DB.Query(`
+        SELECT id, group_id, token, created_by, expires_at, max_uses, uses, revoked, created_at
+        FROM group_invite_links
+        WHERE group_id = ?
+        ORDER BY created_at DESC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.GroupInviteLink
+	for rows.Next() {
+		link, err := scanInviteLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// RevokeInviteLink disables a link so it can no longer be redeemed
+func (repo *GroupInviteRepository) RevokeInviteLink(groupID, linkID int) error {
+	result, err := repo.DB.Exec(`
+        UPDATE group_invite_links SET revoked = 1 WHERE id = ? AND group_id = ?`, linkID, groupID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RedeemInviteLink validates a token, consumes one use and adds the user to the group.
+// It returns the ID of the group that was joined.
+func (repo *GroupInviteRepository) RedeemInviteLink(token string, userID int) (int, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Consume the use first, so the check and the increment are one statement and the transaction
+	// takes the write lock before reading. Later failures roll it back.
+	result, err := tx.Exec(`
+        UPDATE group_invite_links SET uses = uses + 1
+        WHERE token = ? AND revoked = 0 AND (max_uses IS NULL OR uses < max_uses)
+          AND (expires_at IS NULL OR datetime(expires_at) > datetime('now'))`, token)
+	if err != nil {
+		return 0, err
+	}
+	consumed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if consumed == 0 {
+		return 0, ErrInviteLinkUnavailable
+	}
+
+	link, err := scanInviteLink(tx.QueryRow(`
+        SELECT id, group_id, token, created_by, expires_at, max_uses, uses, revoked, created_at
+        FROM group_invite_links WHERE token = ?`, token))
+	if err != nil {
+		return 0, err
+	}
+
+	banned, err := isUserBanned(tx, userID, link.GroupID)
+	if err != nil {
+		return 0, err
+	}
+	if banned {
+		return 0, ErrUserBanned
+	}
+
+	var isMember bool
+	err = tx.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = ? AND user_id = ? AND status != 'pending')`,
+		link.GroupID, userID).Scan(&isMember)
+	if err != nil {
+		return 0, err
+	}
+	if isMember {
+		return 0, ErrAlreadyGroupMember
+	}
+
+	if err := addGroupMember(tx, link.GroupID, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	log.Printf("✅ User %d joined Group %d via invite link %d", userID, link.GroupID, link.ID)
+	return link.GroupID, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInviteLink(row rowScanner) (*models.GroupInviteLink, error) {
+	var link models.GroupInviteLink
+	var expiresAt sql.NullTime
+	var maxUses sql.NullInt64
+
+	err := row.Scan(&link.ID, &link.GroupID, &link.Token, &link.CreatedBy,
+		&expiresAt, &maxUses, &link.Uses, &link.Revoked, &link.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if maxUses.Valid {
+		uses := int(maxUses.Int64)
+		link.MaxUses = &uses
+	}
+	return &link, nil
+}
@@ -14,10 +14,10 @@ func NewGroupMemberRepository(db *sql.DB) *GroupMemberRepository {
 	return &GroupMemberRepository{DB: db}
 }
 
-// AddUserToGroup adds a user to a group
+// AddUserToGroup adds a user to a group with the given role
 func (repo *GroupMemberRepository) AddUserToGroup(groupID, userID int, role string) error {
 	_, err := repo.DB.Exec(`
-		INSERT INTO group_members (group_id, user_id, role) 
+		INSERT INTO group_members (group_id, user_id, status) 
 		VALUES (?, ?, ?)`, groupID, userID, role)
 	return err
 }
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"social-network/internal/models"
+)
+
+// ErrNoPendingTransfer is returned when a user responds to a transfer that isn't waiting on them
+var ErrNoPendingTransfer = errors.New("no pending ownership transfer for you in this group")
+
+// Errors returned when the roles behind a transfer offer are wrong, or changed while it was pending
+var (
+	ErrNotGroupOwner          = errors.New("only the group owner can transfer ownership")
+	ErrTransferTargetNotAdmin = errors.New("ownership can only be transferred to a group admin")
+	ErrTransferOwnerChanged   = errors.New("the offering user is no longer the group owner")
+	ErrTransferAdminChanged   = errors.New("you are no longer an admin of this group")
+)
+
+// RequestOwnershipTransfer offers ownership of a group to one of its admins.
+// Any earlier pending offer for the group is cancelled.
+func (repo *GroupRepository) RequestOwnershipTransfer(groupID, ownerID, adminID int) (*models.GroupOwnershipTransfer, error) {
+	ownerRole, err := repo.GetMemberRole(ownerID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerRole != models.GroupRoleOwner {
+		return nil, ErrNotGroupOwner
+	}
+
+	targetRole, err := repo.GetMemberRole(adminID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if targetRole != models.GroupRoleAdmin {
+		return nil, ErrTransferTargetNotAdmin
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        UPDATE group_ownership_transfers SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+        WHERE group_id = ? AND status = 'pending'`, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+        INSERT INTO group_ownership_transfers (group_id, from_user_id, to_user_id)
+        VALUES (?, ?, ?)`, groupID, ownerID, adminID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.getOwnershipTransfer(int(id))
+}
+
+// GetPendingOwnershipTransfer returns the open transfer offer of a group, or nil if there is none
+func (repo *GroupRepository) GetPendingOwnershipTransfer(groupID int) (*models.GroupOwnershipTransfer, error) {
+	var id int
+	err := repo.DB.QueryRow(`
+        SELECT id FROM group_ownership_transfers WHERE group_id = ? AND status = 'pending'`, groupID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.getOwnershipTransfer(id)
+}
+
+// AcceptOwnershipTransfer makes the invited admin the owner; the previous owner becomes an admin
+func (repo *GroupRepository) AcceptOwnershipTransfer(groupID, userID int) error {
+	transfer, err := repo.GetPendingOwnershipTransfer(groupID)
+	if err != nil {
+		return err
+	}
+	if transfer == nil || transfer.ToUserID != userID {
+		return ErrNoPendingTransfer
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Both sides must still hold the roles they had when the offer was made
+	result, err := tx.Exec(`
+        UPDATE group_members SET status = 'admin'
+        WHERE group_id = ? AND user_id = ? AND status = 'owner'`, groupID, transfer.FromUserID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTransferOwnerChanged
+	}
+
+	result, err = tx.Exec(`
+        UPDATE group_members SET status = 'owner'
+        WHERE group_id = ? AND user_id = ? AND status = 'admin'`, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrTransferAdminChanged
+	}
+
+	if _, err := tx.Exec(`UPDATE groups SET creator_id = ? WHERE id = ?`, userID, groupID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+        UPDATE group_ownership_transfers SET status = 'accepted', responded_at = CURRENT_TIMESTAMP
+        WHERE id = ?`, transfer.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("✅ Group %d ownership transferred from User %d to User %d", groupID, transfer.FromUserID, userID)
+	return nil
+}
+
+// DeclineOwnershipTransfer rejects a pending offer addressed to the user
+func (repo *GroupRepository) DeclineOwnershipTransfer(groupID, userID int) error {
+	result, err := repo.DB.Exec(`
+        UPDATE group_ownership_transfers SET status = 'declined', responded_at = CURRENT_TIMESTAMP
+        WHERE group_id = ? AND to_user_id = ? AND status = 'pending'`, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNoPendingTransfer
+	}
+	return nil
+}
+
+func (repo *GroupRepository) getOwnershipTransfer(transferID int) (*models.GroupOwnershipTransfer, error) {
+	var transfer models.GroupOwnershipTransfer
+	var respondedAt sql.NullTime
+	err := repo.DB.QueryRow(`
+        SELECT id, group_id, from_user_id, to_user_id, status, created_at, responded_at
+        FROM group_ownership_transfers WHERE id = ?`, transferID).
+		Scan(&transfer.ID, &transfer.GroupID, &transfer.FromUserID, &transfer.ToUserID,
+			&transfer.Status, &transfer.CreatedAt, &respondedAt)
+	if err != nil {
+		return nil, err
+	}
+	if respondedAt.Valid {
+		transfer.RespondedAt = &respondedAt.Time
+	}
+	return &transfer, nil
+}
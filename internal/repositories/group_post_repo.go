@@ -15,11 +15,103 @@ func NewGroupPostRepository(db *sql.DB) *GroupPostRepository {
 	return &GroupPostRepository{DB: db}
 }
 
-// CreateGroupPost adds a new post to a group
+// CreateGroupPost adds a new post to a group and fills in its ID
 func (repo *GroupPostRepository) CreateGroupPost(post *models.GroupPost) error {
-	_, err := repo.DB.Exec(`
+	result, err := repo.DB.Exec(`
         INSERT INTO group_posts (group_id, user_id, content, image)
         VALUES (?, ?, ?, ?)`,
 		post.GroupID, post.UserID, post.Content, post.Image)
-	return err
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	post.ID = int(id)
+	return nil
+}
+
+// groupPostColumns selects a post with its author's nickname and like/comment counts
+const groupPostColumns = `
+        SELECT gp.id, gp.group_id, gp.user_id, u.nickname, gp.content, gp.image, gp.created_at,
+            (SELECT COUNT(*) FROM likes l WHERE l.group_post_id = gp.id),
+            (SELECT COUNT(*) FROM comments c WHERE c.group_post_id = gp.id)
+        FROM group_posts gp
+        JOIN users u ON gp.user_id = u.id`
+
+func scanGroupPost(row rowScanner) (*models.GroupPost, error) {
+	var post models.GroupPost
+	err := row.Scan(&post.ID, &post.GroupID, &post.UserID, &post.Nickname, &post.Content, &post.Image,
+		&post.CreatedAt, &post.LikeCount, &post.CommentCount)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetGroupPosts retrieves all posts of a group, newest first
+func (repo *GroupPostRepository) GetGroupPosts(groupID int) ([]models.GroupPost, error) {
+	rows, err := repo.DB.Query(groupPostColumns+`
+        WHERE gp.group_id = ?
+        ORDER BY gp.created_at DESC, gp.id DESC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.GroupPost
+	for rows.Next() {
+		post, err := scanGroupPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+	return posts, rows.Err()
+}
+
+// GetGroupPostByID retrieves a single group post
+func (repo *GroupPostRepository) GetGroupPostByID(postID int) (*models.GroupPost, error) {
+	return scanGroupPost(repo.DB.QueryRow(groupPostColumns+`
+        WHERE gp.id = ?`, postID))
+}
+
+// EditGroupPost updates a post's content (only the author can edit)
+func (repo *GroupPostRepository) EditGroupPost(postID, userID int, content string, image *string) error {
+	result, err := repo.DB.Exec(`
+        UPDATE group_posts SET content = ?, image = ?
+        WHERE id = ? AND user_id = ?`,
+		content, image, postID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteGroupPost removes a post along with its comments and likes
+func (repo *GroupPostRepository) DeleteGroupPost(postID int) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        DELETE FROM likes
+        WHERE group_post_id = ? OR comment_id IN (SELECT id FROM comments WHERE group_post_id = ?)`,
+		postID, postID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM comments WHERE group_post_id = ?", postID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM group_posts WHERE id = ?", postID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
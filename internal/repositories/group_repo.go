@@ -7,6 +7,24 @@ import (
 	"social-network/internal/models"
 )
 
+// ErrNoPendingMembership is returned when approving or rejecting a join request that doesn't exist
+var ErrNoPendingMembership = errors.New("no pending join request from this user")
+
+// Errors returned by SetMemberRole
+var (
+	// ErrInvalidGroupRole is returned for roles that can't be assigned
+	ErrInvalidGroupRole = errors.New("role must be member, moderator or admin")
+	// ErrCannotManageRoles is returned when the acting user's role lacks the manage_roles permission
+	ErrCannotManageRoles = errors.New("you don't have permission to change roles")
+	// ErrRoleOutranked is returned when the target's current or new role isn't below the acting user's
+	ErrRoleOutranked = errors.New("you can only manage roles below your own")
+	// ErrNotGroupMember is returned when the target user isn't an approved member of the group
+	ErrNotGroupMember = errors.New("user is not a member of this group")
+)
+
+// ErrJoinRequestPending is returned when a user asks to join a group they're already waiting to join
+var ErrJoinRequestPending = errors.New("you have already requested to join this group")
+
 // GroupRepository handles database operations related to groups
 type GroupRepository struct {
 	DB *sql.DB
@@ -17,13 +35,40 @@ func NewGroupRepository(db *sql.DB) *GroupRepository {
 	return &GroupRepository{DB: db}
 }
 
-// CreateGroup inserts a new group into the database
+// CreateGroup inserts a new group into the database and makes its creator the owner
 func (repo *GroupRepository) CreateGroup(group *models.Group) error {
-	_, err := repo.DB.Exec(`
-        INSERT INTO groups (name, description, creator_id, created_at) 
-        VALUES (?, ?, ?, CURRENT_TIMESTAMP)`,
-		group.Name, group.Description, group.CreatorID)
-	return err
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if group.Privacy == "" {
+		group.Privacy = models.GroupPrivacyPublic
+	}
+
+	result, err := tx.Exec(`
+        INSERT INTO groups (name, description, creator_id, privacy, created_at) 
+        VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		group.Name, group.Description, group.CreatorID, group.Privacy)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	group.ID = int(id)
+
+	_, err = tx.Exec(`
+        INSERT INTO group_members (group_id, user_id, status) 
+        VALUES (?, ?, 'owner')`, group.ID, group.CreatorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GroupExists checks if a group with the given name exists
@@ -49,30 +94,45 @@ func (repo *GroupRepository) IsUserInGroup(userID, groupID int) (bool, error) {
 
 // ApproveMembership approves a user's membership request
 func (repo *GroupRepository) ApproveMembership(groupID, userID, adminID int) error {
-	isAdmin, err := repo.IsUserGroupAdmin(adminID, groupID)
+	allowed, err := repo.HasGroupPermission(adminID, groupID, models.GroupPermApproveMembers)
 	if err != nil {
 		return err
 	}
-	if !isAdmin {
-		return errors.New("only the group admin can approve members")
+	if !allowed {
+		return errors.New("you don't have permission to approve members")
 	}
 
-	_, err = repo.DB.Exec("UPDATE group_members SET role = 'member' WHERE group_id = ? AND user_id = ? AND role = 'pending'", groupID, userID)
-	return err
+	result, err := repo.DB.Exec("UPDATE group_members SET status = 'member' WHERE group_id = ? AND user_id = ? AND status = 'pending'", groupID, userID)
+	return requirePendingMembership(result, err)
 }
 
 // RejectMembership removes the user from pending requests
 func (repo *GroupRepository) RejectMembership(groupID, userID, adminID int) error {
-	isAdmin, err := repo.IsUserGroupAdmin(adminID, groupID)
+	allowed, err := repo.HasGroupPermission(adminID, groupID, models.GroupPermApproveMembers)
 	if err != nil {
 		return err
 	}
-	if !isAdmin {
-		return errors.New("only the group admin can reject members")
+	if !allowed {
+		return errors.New("you don't have permission to reject members")
 	}
 
-	_, err = repo.DB.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ? AND role = 'pending'", groupID, userID)
-	return err
+	result, err := repo.DB.Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ? AND status = 'pending'", groupID, userID)
+	return requirePendingMembership(result, err)
+}
+
+// requirePendingMembership turns an approve/reject that touched no rows into ErrNoPendingMembership
+func requirePendingMembership(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNoPendingMembership
+	}
+	return nil
 }
 
 // GetGroupMembers retrieves all approved members of a group
@@ -81,7 +141,7 @@ func (repo *GroupRepository) GetGroupMembers(groupID int) ([]models.GroupMember,
         SELECT gm.user_id, u.nickname, gm.status, gm.joined_at 
         FROM group_members gm
         JOIN users u ON gm.user_id = u.id
-        WHERE gm.group_id = ? AND (gm.status = 'member' OR gm.status = 'admin')`, groupID)
+        WHERE gm.group_id = ? AND gm.status != 'pending'`, groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -100,23 +160,247 @@ func (repo *GroupRepository) GetGroupMembers(groupID int) ([]models.GroupMember,
 	return members, nil
 }
 
-// IsUserGroupAdmin checks if a user is the admin of a group
+// IsUserGroupAdmin checks if a user is an admin or the owner of a group
 func (repo *GroupRepository) IsUserGroupAdmin(userID, groupID int) (bool, error) {
-	var isAdmin bool
-	err := repo.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM groups WHERE id = ? AND creator_id = ?)", groupID, userID).Scan(&isAdmin)
-	return isAdmin, err
+	role, err := repo.GetMemberRole(userID, groupID)
+	if err != nil {
+		return false, err
+	}
+	return role == models.GroupRoleAdmin || role == models.GroupRoleOwner, nil
 }
 
-// RequestToJoinGroup allows a user to request membership in a group
-func (repo *GroupRepository) RequestToJoinGroup(groupID, userID int) error {
+// GetMemberRole returns a user's role in a group ("pending" for open requests, "" if not a member)
+func (repo *GroupRepository) GetMemberRole(userID, groupID int) (string, error) {
+	var role string
+	err := repo.DB.QueryRow("SELECT status FROM group_members WHERE user_id = ? AND group_id = ?", userID, groupID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// HasGroupPermission checks the permission matrix for the user's role in a group
+func (repo *GroupRepository) HasGroupPermission(userID, groupID int, permission string) (bool, error) {
+	role, err := repo.GetMemberRole(userID, groupID)
+	if err != nil {
+		return false, err
+	}
+	return models.GroupRoleHasPermission(role, permission), nil
+}
+
+// SetMemberRole changes a member's role. The actor must outrank both the member's
+// current role and the new role, so admins manage moderators and only the owner manages admins.
+func (repo *GroupRepository) SetMemberRole(groupID, userID, actorID int, role string) error {
+	if role != models.GroupRoleMember && role != models.GroupRoleModerator && role != models.GroupRoleAdmin {
+		return ErrInvalidGroupRole
+	}
+
+	actorRole, err := repo.GetMemberRole(actorID, groupID)
+	if err != nil {
+		return err
+	}
+	if !models.GroupRoleHasPermission(actorRole, models.GroupPermManageRoles) {
+		return ErrCannotManageRoles
+	}
+
+	currentRole, err := repo.GetMemberRole(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if models.GroupRoleRank(currentRole) == 0 {
+		return ErrNotGroupMember
+	}
+
+	actorRank := models.GroupRoleRank(actorRole)
+	if models.GroupRoleRank(currentRole) >= actorRank || models.GroupRoleRank(role) >= actorRank {
+		return ErrRoleOutranked
+	}
+
+	_, err = repo.DB.Exec("UPDATE group_members SET status = ? WHERE group_id = ? AND user_id = ?", role, groupID, userID)
+	return err
+}
+
+// RequestToJoinGroup lets a user join a public group instantly or request membership
+// in a private one. It returns the resulting status ("member" or "pending"), or
+// ErrJoinRequestPending / ErrAlreadyGroupMember if the user already has a membership row.
+func (repo *GroupRepository) RequestToJoinGroup(groupID, userID int) (string, error) {
 	log.Println("Inserting into group_members:", groupID, userID) // Debug Log
 
-	_, err := repo.DB.Exec(`
-        INSERT INTO group_members (group_id, user_id, status) 
-        VALUES (?, ?, 'pending')`, groupID, userID)
+	banned, err := repo.IsUserBanned(userID, groupID)
+	if err != nil {
+		return "", err
+	}
+	if banned {
+		return "", ErrUserBanned
+	}
+
+	var privacy string
+	if err := repo.DB.QueryRow("SELECT privacy FROM groups WHERE id = ?", groupID).Scan(&privacy); err != nil {
+		return "", err
+	}
 
+	status := models.GroupRolePending
+	if privacy == models.GroupPrivacyPublic {
+		status = models.GroupRoleMember
+	}
+
+	result, err := repo.DB.Exec(`
+        INSERT INTO group_members (group_id, user_id, status) 
+        VALUES (?, ?, ?)
+        ON CONFLICT(group_id, user_id) DO NOTHING`, groupID, userID, status)
 	if err != nil {
 		log.Println("❌ Failed to request to join group:", err) // Show exact error
+		return "", err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 1 {
+		return status, err
+	}
+
+	existing, err := repo.GetMemberRole(userID, groupID)
+	if err != nil {
+		return "", err
+	}
+	if existing == models.GroupRolePending {
+		return "", ErrJoinRequestPending
+	}
+	return "", ErrAlreadyGroupMember
+}
+
+// IsGroupMember checks if a user is an approved (non-pending) member of a group
+func (repo *GroupRepository) IsGroupMember(userID, groupID int) (bool, error) {
+	var exists bool
+	err := repo.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM group_members WHERE user_id = ? AND group_id = ? AND status != 'pending')", userID, groupID).Scan(&exists)
+	return exists, err
+}
+
+// AddMember adds a user to a group as an approved member, promoting a pending request if one exists
+func (repo *GroupRepository) AddMember(groupID, userID int) error {
+	return addGroupMember(repo.DB, groupID, userID)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// addGroupMember inserts an approved membership row, leaving existing members untouched
+func addGroupMember(db execer, groupID, userID int) error {
+	_, err := db.Exec(`
+        INSERT INTO group_members (group_id, user_id, status)
+        VALUES (?, ?, 'member')
+        ON CONFLICT(group_id, user_id) DO UPDATE SET status = 'member' WHERE status = 'pending'`,
+		groupID, userID)
+	if err != nil {
+		log.Println("❌ Failed to add group member:", err)
+	}
+	return err
+}
+
+// GetGroupByID retrieves a group's details by ID
+func (repo *GroupRepository) GetGroupByID(groupID int) (*models.Group, error) {
+	var group models.Group
+	var description sql.NullString
+	var archivedAt sql.NullTime
+	err := repo.DB.QueryRow(`
+        SELECT id, name, description, creator_id, privacy, created_at, archived_at
+        FROM groups WHERE id = ?`, groupID).
+		Scan(&group.ID, &group.Name, &description, &group.CreatorID, &group.Privacy, &group.CreatedAt, &archivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Description = description.String
+	if archivedAt.Valid {
+		group.ArchivedAt = &archivedAt.Time
+	}
+	return &group, nil
+}
+
+// UpdateGroup edits a group's name, description and privacy
+func (repo *GroupRepository) UpdateGroup(group *models.Group) error {
+	_, err := repo.DB.Exec(`
+        UPDATE groups SET name = ?, description = ?, privacy = ? WHERE id = ?`,
+		group.Name, group.Description, group.Privacy, group.ID)
+	return err
+}
+
+// CanViewGroupContent checks if a user may see a group's posts, events, chat and members:
+// anyone can for public groups, only approved members can for private ones
+func (repo *GroupRepository) CanViewGroupContent(userID, groupID int) (bool, error) {
+	var privacy string
+	if err := repo.DB.QueryRow("SELECT privacy FROM groups WHERE id = ?", groupID).Scan(&privacy); err != nil {
+		return false, err
+	}
+	if privacy == models.GroupPrivacyPublic {
+		return true, nil
+	}
+	return repo.IsGroupMember(userID, groupID)
+}
+
+// IsGroupArchived checks if a group has been archived (read-only)
+func (repo *GroupRepository) IsGroupArchived(groupID int) (bool, error) {
+	var archived bool
+	err := repo.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM groups WHERE id = ? AND archived_at IS NOT NULL)", groupID).Scan(&archived)
+	return archived, err
+}
+
+// SetGroupArchived archives or restores a group
+func (repo *GroupRepository) SetGroupArchived(groupID int, archived bool) error {
+	query := "UPDATE groups SET archived_at = NULL WHERE id = ?"
+	if archived {
+		query = "UPDATE groups SET archived_at = CURRENT_TIMESTAMP WHERE id = ? AND archived_at IS NULL"
 	}
+	_, err := repo.DB.Exec(query, groupID)
 	return err
 }
+
+// groupNotificationsCondition matches the notifications about a group (?1) and its chat, events,
+// posts and comments on its posts
+const groupNotificationsCondition = `(entity_type IN ('group', 'group_chat') AND entity_id = ?1)
+            OR (entity_type = 'event' AND entity_id IN (SELECT id FROM group_events WHERE group_id = ?1))
+            OR (entity_type = 'group_post' AND entity_id IN (SELECT id FROM group_posts WHERE group_id = ?1))
+            OR (entity_type = 'comment' AND entity_id IN (SELECT c.id FROM comments c
+                JOIN group_posts gp ON c.group_post_id = gp.id WHERE gp.group_id = ?1))`
+
+// DeleteGroup permanently removes a group together with its posts, events, occurrence changes,
+// RSVPs, sent reminders, chat history, memberships, bans, invite links, notifications and mutes
+func (repo *GroupRepository) DeleteGroup(groupID int) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DELETE FROM notification_actors WHERE notification_id IN (SELECT id FROM notifications WHERE " + groupNotificationsCondition + ")",
+		"DELETE FROM notifications WHERE " + groupNotificationsCondition,
+		`DELETE FROM notification_mutes WHERE (entity_type IN ('group', 'group_chat') AND entity_id = ?1)
+            OR (entity_type = 'event' AND entity_id IN (SELECT id FROM group_events WHERE group_id = ?1))`,
+		"DELETE FROM event_rsvps WHERE event_id IN (SELECT id FROM group_events WHERE group_id = ?)",
+		"DELETE FROM group_event_occurrences WHERE event_id IN (SELECT id FROM group_events WHERE group_id = ?)",
+		"DELETE FROM event_reminders_sent WHERE event_id IN (SELECT id FROM group_events WHERE group_id = ?)",
+		"DELETE FROM group_events WHERE group_id = ?",
+		`DELETE FROM likes WHERE group_post_id IN (SELECT id FROM group_posts WHERE group_id = ?1)
+            OR comment_id IN (SELECT c.id FROM comments c JOIN group_posts gp ON c.group_post_id = gp.id WHERE gp.group_id = ?1)`,
+		"DELETE FROM comments WHERE group_post_id IN (SELECT id FROM group_posts WHERE group_id = ?)",
+		"DELETE FROM group_posts WHERE group_id = ?",
+		"DELETE FROM group_chat_messages WHERE group_id = ?",
+		"DELETE FROM group_invite_links WHERE group_id = ?",
+		`DELETE FROM webhook_delivery_attempts WHERE delivery_id IN (SELECT d.id FROM webhook_deliveries d
+            JOIN webhooks w ON d.webhook_id = w.id WHERE w.group_id = ?)`,
+		"DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE group_id = ?)",
+		"DELETE FROM webhooks WHERE group_id = ?",
+		"DELETE FROM group_ownership_transfers WHERE group_id = ?",
+		"DELETE FROM group_bans WHERE group_id = ?",
+		"DELETE FROM group_members WHERE group_id = ?",
+		"DELETE FROM groups WHERE id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, groupID); err != nil {
+			log.Printf("❌ Failed to delete Group %d: %v", groupID, err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
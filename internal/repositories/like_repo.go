@@ -15,13 +15,13 @@ func NewLikeRepository(db *sql.DB) *LikeRepository {
 	return &LikeRepository{DB: db}
 }
 
-// ToggleLike adds or removes a like for a post or comment
-func (repo *LikeRepository) ToggleLike(userID, postID, commentID int) (bool, error) {
+// ToggleLike adds or removes a like for a post, comment or group post
+func (repo *LikeRepository) ToggleLike(userID, postID, commentID, groupPostID int) (bool, error) {
 	// Check if the like already exists
 	var exists bool
 	err := repo.DB.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND post_id IS ? AND comment_id IS ?)`,
-		userID, postID, commentID).Scan(&exists)
+		SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND IFNULL(post_id, 0) = ? AND IFNULL(comment_id, 0) = ? AND IFNULL(group_post_id, 0) = ?)`,
+		userID, postID, commentID, groupPostID).Scan(&exists)
 
 	if err != nil {
 		log.Println("Error checking like existence:", err)
@@ -30,8 +30,8 @@ func (repo *LikeRepository) ToggleLike(userID, postID, commentID int) (bool, err
 
 	if exists {
 		// Remove the like
-		_, err := repo.DB.Exec(`DELETE FROM likes WHERE user_id = ? AND post_id IS ? AND comment_id IS ?`,
-			userID, postID, commentID)
+		_, err := repo.DB.Exec(`DELETE FROM likes WHERE user_id = ? AND IFNULL(post_id, 0) = ? AND IFNULL(comment_id, 0) = ? AND IFNULL(group_post_id, 0) = ?`,
+			userID, postID, commentID, groupPostID)
 		if err != nil {
 			log.Println("Error removing like:", err)
 			return false, err
@@ -39,9 +39,10 @@ func (repo *LikeRepository) ToggleLike(userID, postID, commentID int) (bool, err
 		return false, nil // Indicates unlike
 	}
 
-	// Add the like
-	_, err = repo.DB.Exec(`INSERT INTO likes (user_id, post_id, comment_id) VALUES (?, ?, ?)`,
-		userID, postID, commentID)
+	// Add the like; a concurrent request may have added it already
+	_, err = repo.DB.Exec(`INSERT INTO likes (user_id, post_id, comment_id, group_post_id) VALUES (?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0))
+		ON CONFLICT DO NOTHING`,
+		userID, postID, commentID, groupPostID)
 	if err != nil {
 		log.Println("Error inserting like:", err)
 		return false, err
@@ -50,12 +51,12 @@ func (repo *LikeRepository) ToggleLike(userID, postID, commentID int) (bool, err
 	return true, nil // Indicates like added
 }
 
-// GetLikeCount retrieves the number of likes for a post or comment
-func (repo *LikeRepository) GetLikeCount(postID, commentID int) (int, error) {
+// GetLikeCount retrieves the number of likes for a post, comment or group post
+func (repo *LikeRepository) GetLikeCount(postID, commentID, groupPostID int) (int, error) {
 	var count int
 	err := repo.DB.QueryRow(`
-		SELECT COUNT(*) FROM likes WHERE post_id IS ? AND comment_id IS ?`,
-		postID, commentID).Scan(&count)
+		SELECT COUNT(*) FROM likes WHERE IFNULL(post_id, 0) = ? AND IFNULL(comment_id, 0) = ? AND IFNULL(group_post_id, 0) = ?`,
+		postID, commentID, groupPostID).Scan(&count)
 
 	if err != nil {
 		log.Println("Error counting likes:", err)
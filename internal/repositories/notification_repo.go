@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
+	"time"
 
 	"social-network/internal/models"
 )
@@ -29,52 +32,314 @@ func (repo *NotificationRepository) CreateNotification(userID int, notifType str
 	return err
 }
 
-// GetNotifications fetches all notifications for a user.
-func (repo *NotificationRepository) GetNotifications(userID int) ([]models.Notification, error) {
-	rows, err := repo.DB.Query(`
-		SELECT id, user_id, type, message, is_read, created_at 
-		FROM notifications 
-		WHERE user_id = ? ORDER BY created_at DESC`, userID)
+// SaveNotification inserts a structured notification and fills in its ID and creation time.
+func (repo *NotificationRepository) SaveNotification(notification *models.Notification) error {
+	var data interface{}
+	if len(notification.Data) > 0 {
+		encoded, err := json.Marshal(notification.Data)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+
+	result, err := repo.DB.Exec(`
+        INSERT INTO notifications (user_id, type, message, actor_id, entity_type, entity_id, data, state, in_app, is_read, created_at)
+        VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, 0), ?, NULLIF(?, ''), ?, 0, CURRENT_TIMESTAMP)`,
+		notification.UserID, notification.Type, notification.Message,
+		notification.ActorID, notification.EntityType, notification.EntityID, data, notification.State, !notification.Hidden)
+	if err != nil {
+		log.Println("❌ Error inserting notification:", err)
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	notification.ID = int(id)
+	notification.SetActions()
+	if models.IsAggregatable(notification.Type) && notification.ActorID != 0 {
+		_, err = repo.DB.Exec(`
+			INSERT INTO notification_actors (notification_id, actor_id) VALUES (?, ?)`, id, notification.ActorID)
+		if err != nil {
+			return err
+		}
+		notification.ActorCount = 1
+	}
+	return repo.DB.QueryRow(`SELECT created_at FROM notifications WHERE id = ?`, id).Scan(&notification.CreatedAt)
+}
+
+// AggregateNotification folds a like or comment notification into the recipient's unread
+// notification of the same type on the same entity, if one was started within the window. On
+// success the notification is replaced by the updated aggregate; otherwise it's left untouched
+// and should be saved as a new notification.
+func (repo *NotificationRepository) AggregateNotification(notification *models.Notification, window time.Duration) (bool, error) {
+	var data interface{}
+	if len(notification.Data) > 0 {
+		encoded, err := json.Marshal(notification.Data)
+		if err != nil {
+			return false, err
+		}
+		data = string(encoded)
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		SELECT id FROM notifications
+		WHERE user_id = ? AND type = ? AND entity_type = ? AND entity_id = ? AND in_app = ? AND is_read = 0
+		  AND datetime(created_at) >= datetime('now', ?)
+		ORDER BY id DESC LIMIT 1`,
+		notification.UserID, notification.Type, notification.EntityType, notification.EntityID, !notification.Hidden,
+		fmt.Sprintf("-%d seconds", int(window/time.Second))).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT OR REPLACE INTO notification_actors (notification_id, actor_id, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)`, id, notification.ActorID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE notifications SET data = ? WHERE id = ?`, data, id); err != nil {
+		return false, err
+	}
+	if _, err := refreshAggregate(tx, id); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	aggregated, err := repo.GetNotification(notification.UserID, id)
+	if err != nil {
+		return false, err
+	}
+	*notification = *aggregated
+	return true, nil
+}
+
+// WithdrawActor takes an actor out of the aggregated notifications about an entity, e.g. when a
+// like is removed. Notifications left with other actors are returned in their updated form in
+// updated; those left without actors are deleted and returned in removed, identified by ID.
+func (repo *NotificationRepository) WithdrawActor(notifType, entityType string, entityID, actorID int) (updated, removed []models.Notification, err error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT n.id, n.user_id, n.in_app FROM notifications n
+		JOIN notification_actors na ON na.notification_id = n.id
+		WHERE n.type = ? AND n.entity_type = ? AND n.entity_id = ? AND na.actor_id = ?`,
+		notifType, entityType, entityID, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var notifications []models.Notification
+	for rows.Next() {
+		n := models.Notification{Type: notifType, EntityType: entityType, EntityID: entityID}
+		var inApp bool
+		if err := rows.Scan(&n.ID, &n.UserID, &inApp); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		n.Hidden = !inApp
+		notifications = append(notifications, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var remaining []models.Notification
+	for _, n := range notifications {
+		_, err := tx.Exec(`DELETE FROM notification_actors WHERE notification_id = ? AND actor_id = ?`, n.ID, actorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		count, err := refreshAggregate(tx, n.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if count > 0 {
+			remaining = append(remaining, n)
+		} else {
+			removed = append(removed, n)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, n := range remaining {
+		notification, err := repo.GetNotification(n.UserID, n.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		updated = append(updated, *notification)
+	}
+	return updated, removed, nil
+}
+
+// refreshAggregate recounts an aggregated notification's actors and rewrites its latest actor and
+// message from the "action" in its data. A notification with no actors left is deleted. It
+// returns the number of actors remaining.
+func refreshAggregate(tx *sql.Tx, notificationID int) (int, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM notification_actors WHERE notification_id = ?`, notificationID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		_, err := tx.Exec(`DELETE FROM notifications WHERE id = ?`, notificationID)
+		return 0, err
+	}
+
+	var latestID int
+	var latestNickname string
+	err = tx.QueryRow(`
+		SELECT na.actor_id, u.nickname FROM notification_actors na JOIN users u ON na.actor_id = u.id
+		WHERE na.notification_id = ?
+		ORDER BY na.created_at DESC, na.rowid DESC LIMIT 1`, notificationID).Scan(&latestID, &latestNickname)
+	if err != nil {
+		return 0, err
+	}
+
+	var message string
+	var data sql.NullString
+	err = tx.QueryRow(`SELECT message, data FROM notifications WHERE id = ?`, notificationID).Scan(&message, &data)
+	if err != nil {
+		return 0, err
+	}
+	var details struct {
+		Action string `json:"action"`
+	}
+	if data.Valid && json.Unmarshal([]byte(data.String), &details) == nil && details.Action != "" {
+		message = models.AggregatedMessage(latestNickname, count, details.Action)
+	}
+
+	_, err = tx.Exec(`UPDATE notifications SET actor_id = ?, actor_count = ?, message = ? WHERE id = ?`,
+		latestID, count, message, notificationID)
+	return count, err
+}
+
+// notificationColumns lists the columns scanned by scanNotification; queries alias notifications as n
+const notificationColumns = `n.id, n.user_id, n.type, n.message, IFNULL(n.actor_id, 0), IFNULL(u.nickname, ''),
+    n.actor_count, IFNULL(n.entity_type, ''), IFNULL(n.entity_id, 0), n.data, IFNULL(n.state, ''), n.in_app, n.is_read, n.created_at`
+
+// notificationFrom joins the actor so clients can show their nickname
+const notificationFrom = ` FROM notifications n LEFT JOIN users u ON n.actor_id = u.id `
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var notif models.Notification
+	var data sql.NullString
+	var inApp bool
+	err := row.Scan(&notif.ID, &notif.UserID, &notif.Type, &notif.Message, &notif.ActorID, &notif.ActorNickname,
+		&notif.ActorCount, &notif.EntityType, &notif.EntityID, &data, &notif.State, &inApp, &notif.IsRead, &notif.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	notif.Hidden = !inApp
+	if !models.IsAggregatable(notif.Type) {
+		notif.ActorCount = 0
+	}
+	notif.SetActions()
+	if data.Valid && data.String != "" {
+		if err := json.Unmarshal([]byte(data.String), &notif.Data); err != nil {
+			log.Printf("⚠️ Ignoring malformed data on notification %d: %v", notif.ID, err)
+		}
+	}
+	return &notif, nil
+}
+
+// queryNotifications runs a notification query and scans every row
+func (repo *NotificationRepository) queryNotifications(query string, args ...interface{}) ([]models.Notification, error) {
+	rows, err := repo.DB.Query(query, args...)
 	if err != nil {
-		log.Printf("❌ Error retrieving notifications for User %d: %v", userID, err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	var notifications []models.Notification
 	for rows.Next() {
-		var notif models.Notification
-		if err := rows.Scan(&notif.ID, &notif.UserID, &notif.Type, &notif.Message, &notif.IsRead, &notif.CreatedAt); err != nil {
+		notif, err := scanNotification(rows)
+		if err != nil {
 			log.Println("❌ Error scanning notification row:", err)
 			continue
 		}
-		notifications = append(notifications, notif)
+		notifications = append(notifications, *notif)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-	log.Printf("📩 Retrieved %d notifications for User %d", len(notifications), userID)
+	for i := range notifications {
+		if err := repo.loadRecentActors(&notifications[i]); err != nil {
+			return nil, err
+		}
+	}
 	return notifications, nil
 }
 
-// GetUnreadNotifications fetches unread notifications for a user.
-func (repo *NotificationRepository) GetUnreadNotifications(userID int) ([]models.Notification, error) {
+// loadRecentActors lists the latest users behind an aggregated notification
+func (repo *NotificationRepository) loadRecentActors(notification *models.Notification) error {
+	notification.RecentActors = nil
+	if notification.ActorCount < 2 {
+		return nil
+	}
 	rows, err := repo.DB.Query(`
-		SELECT id, user_id, type, message, is_read, created_at 
-		FROM notifications 
-		WHERE user_id = ? AND is_read = 0 ORDER BY created_at DESC`, userID)
+		SELECT na.actor_id, u.nickname FROM notification_actors na JOIN users u ON na.actor_id = u.id
+		WHERE na.notification_id = ?
+		ORDER BY na.created_at DESC, na.rowid DESC LIMIT ?`, notification.ID, models.MaxRecentActors)
 	if err != nil {
-		log.Printf("❌ Error retrieving unread notifications for User %d: %v", userID, err)
-		return nil, err
+		return err
 	}
 	defer rows.Close()
-
-	var notifications []models.Notification
 	for rows.Next() {
-		var notif models.Notification
-		if err := rows.Scan(&notif.ID, &notif.UserID, &notif.Type, &notif.Message, &notif.IsRead, &notif.CreatedAt); err != nil {
-			log.Println("❌ Error scanning unread notification row:", err)
-			continue
+		var actor models.NotificationActor
+		if err := rows.Scan(&actor.ID, &actor.Nickname); err != nil {
+			return err
 		}
-		notifications = append(notifications, notif)
+		notification.RecentActors = append(notification.RecentActors, actor)
+	}
+	return rows.Err()
+}
+
+// GetNotifications fetches all notifications for a user.
+func (repo *NotificationRepository) GetNotifications(userID int) ([]models.Notification, error) {
+	notifications, err := repo.queryNotifications(`
+		SELECT `+notificationColumns+notificationFrom+`
+		WHERE n.user_id = ? AND n.in_app = 1 ORDER BY n.created_at DESC, n.id DESC`, userID)
+	if err != nil {
+		log.Printf("❌ Error retrieving notifications for User %d: %v", userID, err)
+		return nil, err
+	}
+
+	log.Printf("📩 Retrieved %d notifications for User %d", len(notifications), userID)
+	return notifications, nil
+}
+
+// GetUnreadNotifications fetches unread notifications for a user.
+func (repo *NotificationRepository) GetUnreadNotifications(userID int) ([]models.Notification, error) {
+	notifications, err := repo.queryNotifications(`
+		SELECT `+notificationColumns+notificationFrom+`
+		WHERE n.user_id = ? AND n.in_app = 1 AND n.is_read = 0 ORDER BY n.created_at DESC, n.id DESC`, userID)
+	if err != nil {
+		log.Printf("❌ Error retrieving unread notifications for User %d: %v", userID, err)
+		return nil, err
 	}
 	log.Printf("📩 Retrieved %d unread notifications for User %d", len(notifications), userID)
 	return notifications, nil
@@ -91,3 +356,133 @@ func (repo *NotificationRepository) MarkNotificationsAsRead(userID int) error {
 	log.Printf("✅ Marked all notifications as read for User %d", userID)
 	return nil
 }
+
+// GetNotificationsPage fetches a page of a user's notifications, newest first. Pass the ID of the
+// last notification from the previous page as beforeID (0 for the first page).
+func (repo *NotificationRepository) GetNotificationsPage(userID int, unreadOnly bool, beforeID, limit int) ([]models.Notification, error) {
+	notifications, err := repo.queryNotifications(`
+		SELECT `+notificationColumns+notificationFrom+`
+		WHERE n.user_id = ?1 AND n.in_app = 1 AND (?2 = 0 OR n.is_read = 0) AND (?3 = 0 OR n.id < ?3)
+		ORDER BY n.id DESC LIMIT ?4`, userID, unreadOnly, beforeID, limit)
+	if err != nil {
+		log.Printf("❌ Error retrieving notifications for User %d: %v", userID, err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// CountUnread returns how many unread notifications a user has.
+func (repo *NotificationRepository) CountUnread(userID int) (int, error) {
+	var count int
+	err := repo.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND in_app = 1 AND is_read = 0`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationsRead marks some of a user's notifications as read and returns how many changed.
+// IDs belonging to other users are ignored.
+func (repo *NotificationRepository) MarkNotificationsRead(userID int, ids []int) (int, error) {
+	changed := 0
+	for _, id := range ids {
+		result, err := repo.DB.Exec(`
+			UPDATE notifications SET is_read = 1 WHERE id = ? AND user_id = ? AND is_read = 0`, id, userID)
+		if err != nil {
+			return changed, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return changed, err
+		}
+		changed += int(affected)
+	}
+	return changed, nil
+}
+
+// DeleteNotification dismisses one of a user's notifications; it returns sql.ErrNoRows if the
+// notification doesn't exist or belongs to someone else.
+func (repo *NotificationRepository) DeleteNotification(userID, notificationID int) error {
+	result, err := repo.DB.Exec(`DELETE FROM notifications WHERE id = ? AND user_id = ?`, notificationID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = repo.DB.Exec(`DELETE FROM notification_actors WHERE notification_id = ?`, notificationID)
+	return err
+}
+
+// GetNotification fetches one of a user's notifications; it returns sql.ErrNoRows if the
+// notification doesn't exist or belongs to someone else.
+func (repo *NotificationRepository) GetNotification(userID, notificationID int) (*models.Notification, error) {
+	notification, err := scanNotification(repo.DB.QueryRow(`
+		SELECT `+notificationColumns+notificationFrom+`
+		WHERE n.id = ? AND n.user_id = ?`, notificationID, userID))
+	if err != nil {
+		return nil, err
+	}
+	return notification, repo.loadRecentActors(notification)
+}
+
+// ResolveRequestNotifications records the outcome of a request on every pending notification about
+// it (a join request notifies all group admins, for example) and marks them read. It returns the
+// users whose notifications changed.
+func (repo *NotificationRepository) ResolveRequestNotifications(notifType, entityType string, entityID, actorID int, state string) ([]int, error) {
+	userIDs, err := repo.notificationRecipients(`
+		WHERE type = ? AND entity_type = ? AND entity_id = ? AND actor_id = ? AND state = ?`,
+		notifType, entityType, entityID, actorID, models.NotificationStatePending)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.DB.Exec(`
+		UPDATE notifications SET state = ?, is_read = 1
+		WHERE type = ? AND entity_type = ? AND entity_id = ? AND actor_id = ? AND state = ?`,
+		state, notifType, entityType, entityID, actorID, models.NotificationStatePending)
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// notificationRecipients returns the users holding notifications that match a WHERE clause
+func (repo *NotificationRepository) notificationRecipients(condition string, args ...interface{}) ([]int, error) {
+	rows, err := repo.DB.Query(`SELECT DISTINCT user_id FROM notifications `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// GetEmailDigestNotifications returns a user's unread notifications created in (since, until] whose
+// type they want in their email digest, newest first. Notifications hidden from the app are included.
+func (repo *NotificationRepository) GetEmailDigestNotifications(userID int, since, until string) ([]models.Notification, error) {
+	return repo.queryNotifications(`
+		SELECT `+notificationColumns+notificationFrom+`
+		WHERE n.user_id = ?1 AND n.is_read = 0
+		  AND datetime(n.created_at) > ?2 AND datetime(n.created_at) <= ?3
+		  AND n.type IN (SELECT type FROM notification_preferences WHERE user_id = ?1 AND email_digest = 1)
+		ORDER BY n.id DESC`, userID, since, until)
+}
+
+// GetNotificationsAfter returns a user's notifications with IDs above afterID, oldest first, so a
+// reconnecting client can catch up on what it missed
+func (repo *NotificationRepository) GetNotificationsAfter(userID, afterID, limit int) ([]models.Notification, error) {
+	return repo.queryNotifications(`
+		SELECT `+notificationColumns+notificationFrom+`
+		WHERE n.user_id = ? AND n.in_app = 1 AND n.id > ?
+		ORDER BY n.id ASC LIMIT ?`, userID, afterID, limit)
+}
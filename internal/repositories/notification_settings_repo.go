@@ -0,0 +1,355 @@
+package repositories
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"social-network/internal/models"
+)
+
+// NotificationSettingsRepository handles per-user notification settings
+type NotificationSettingsRepository struct {
+	DB *sql.DB
+}
+
+// NewNotificationSettingsRepository creates a new instance of NotificationSettingsRepository
+func NewNotificationSettingsRepository(db *sql.DB) *NotificationSettingsRepository {
+	return &NotificationSettingsRepository{DB: db}
+}
+
+// GetSettings returns a user's settings, falling back to the defaults if they never changed them
+func (repo *NotificationSettingsRepository) GetSettings(userID int) (*models.NotificationSettings, error) {
+	settings := models.NotificationSettings{UserID: userID, ReminderOffsets: models.DefaultReminderOffsets}
+	var offsets string
+	err := repo.DB.QueryRow(`SELECT rsvp_digest, reminder_offsets FROM notification_settings WHERE user_id = ?`, userID).
+		Scan(&settings.RSVPDigest, &offsets)
+	if err == sql.ErrNoRows {
+		return &settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings.ReminderOffsets = parseReminderOffsets(offsets)
+	return &settings, nil
+}
+
+// SetReminderOffsets stores the minutes before an event at which the user wants reminders;
+// an empty list turns reminders off
+func (repo *NotificationSettingsRepository) SetReminderOffsets(userID int, offsets []int) error {
+	parts := make([]string, len(offsets))
+	for i, offset := range offsets {
+		parts[i] = strconv.Itoa(offset)
+	}
+	_, err := repo.DB.Exec(`
+        INSERT INTO notification_settings (user_id, reminder_offsets) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET reminder_offsets = excluded.reminder_offsets`,
+		userID, strings.Join(parts, ","))
+	return err
+}
+
+// parseReminderOffsets reads the stored comma-separated offsets, skipping anything malformed
+func parseReminderOffsets(value string) []int {
+	offsets := []int{}
+	for _, part := range strings.Split(value, ",") {
+		if offset, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && offset > 0 {
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets
+}
+
+// SetRSVPDigest turns the RSVP digest on or off. Turning it on starts the digest window now,
+// so RSVPs that were already notified individually aren't repeated.
+func (repo *NotificationSettingsRepository) SetRSVPDigest(userID int, enabled bool) error {
+	_, err := repo.DB.Exec(`
+        INSERT INTO notification_settings (user_id, rsvp_digest, rsvp_digest_sent_at)
+        VALUES (?1, ?2, CURRENT_TIMESTAMP)
+        ON CONFLICT(user_id) DO UPDATE SET
+            rsvp_digest = excluded.rsvp_digest,
+            rsvp_digest_sent_at = CASE WHEN notification_settings.rsvp_digest = 0 AND excluded.rsvp_digest = 1
+                THEN CURRENT_TIMESTAMP ELSE notification_settings.rsvp_digest_sent_at END`,
+		userID, enabled)
+	return err
+}
+
+// TakeRSVPDigest collects the RSVPs to a user's events since their last digest and moves
+// the digest window forward, so each RSVP is reported once
+func (repo *NotificationSettingsRepository) TakeRSVPDigest(userID int) ([]models.RSVPActivity, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var since sql.NullString
+	var until string
+	err = tx.QueryRow(`
+        SELECT datetime(rsvp_digest_sent_at), datetime('now') FROM notification_settings WHERE user_id = ?`, userID).
+		Scan(&since, &until)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+        SELECT e.id, e.title, r.user_id, u.nickname, r.status
+        FROM event_rsvps r
+        JOIN group_events e ON r.event_id = e.id
+        JOIN users u ON r.user_id = u.id
+        WHERE e.creator_id = ?1 AND r.user_id != ?1
+          AND datetime(r.updated_at) > COALESCE(?2, '') AND datetime(r.updated_at) <= ?3
+        ORDER BY r.updated_at ASC, r.id ASC`, userID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	var activity []models.RSVPActivity
+	for rows.Next() {
+		var a models.RSVPActivity
+		if err := rows.Scan(&a.EventID, &a.EventTitle, &a.UserID, &a.Nickname, &a.Status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		activity = append(activity, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE notification_settings SET rsvp_digest_sent_at = ? WHERE user_id = ?`, until, userID); err != nil {
+		return nil, err
+	}
+	return activity, tx.Commit()
+}
+
+// GetRSVPDigestSubscribers returns the users who opted into the RSVP digest
+func (repo *NotificationSettingsRepository) GetRSVPDigestSubscribers() ([]int, error) {
+	rows, err := repo.DB.Query(`SELECT user_id FROM notification_settings WHERE rsvp_digest = 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// GetPreferences returns the user's channel choices for every notification type
+func (repo *NotificationSettingsRepository) GetPreferences(userID int) ([]models.NotificationPreference, error) {
+	rows, err := repo.DB.Query(`
+        SELECT type, in_app, push, email_digest FROM notification_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]models.NotificationPreference)
+	for rows.Next() {
+		var pref models.NotificationPreference
+		if err := rows.Scan(&pref.Type, &pref.InApp, &pref.Push, &pref.EmailDigest); err != nil {
+			return nil, err
+		}
+		stored[pref.Type] = pref
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, notifType := range models.NotificationTypes {
+		pref, ok := stored[notifType]
+		if !ok {
+			pref = models.DefaultNotificationPreference(notifType)
+		}
+		preferences = append(preferences, pref)
+	}
+	return preferences, nil
+}
+
+// GetPreference returns the user's channel choices for one notification type
+func (repo *NotificationSettingsRepository) GetPreference(userID int, notifType string) (models.NotificationPreference, error) {
+	pref := models.NotificationPreference{Type: notifType}
+	err := repo.DB.QueryRow(`
+        SELECT in_app, push, email_digest FROM notification_preferences WHERE user_id = ? AND type = ?`, userID, notifType).
+		Scan(&pref.InApp, &pref.Push, &pref.EmailDigest)
+	if err == sql.ErrNoRows {
+		return models.DefaultNotificationPreference(notifType), nil
+	}
+	return pref, err
+}
+
+// SetPreference stores the user's channel choices for a notification type
+func (repo *NotificationSettingsRepository) SetPreference(userID int, pref models.NotificationPreference) error {
+	_, err := repo.DB.Exec(`
+        INSERT INTO notification_preferences (user_id, type, in_app, push, email_digest) VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(user_id, type) DO UPDATE SET
+            in_app = excluded.in_app, push = excluded.push, email_digest = excluded.email_digest`,
+		userID, pref.Type, pref.InApp, pref.Push, pref.EmailDigest)
+	return err
+}
+
+// GetMutes lists what the user has muted, most recent first
+func (repo *NotificationSettingsRepository) GetMutes(userID int) ([]models.NotificationMute, error) {
+	rows, err := repo.DB.Query(`
+        SELECT entity_type, entity_id, created_at FROM notification_mutes
+        WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mutes := []models.NotificationMute{}
+	for rows.Next() {
+		var mute models.NotificationMute
+		if err := rows.Scan(&mute.EntityType, &mute.EntityID, &mute.CreatedAt); err != nil {
+			return nil, err
+		}
+		mutes = append(mutes, mute)
+	}
+	return mutes, rows.Err()
+}
+
+// Mute silences notifications about a group, event or group chat; muting twice is a no-op
+func (repo *NotificationSettingsRepository) Mute(userID int, entityType string, entityID int) error {
+	_, err := repo.DB.Exec(`
+        INSERT OR IGNORE INTO notification_mutes (user_id, entity_type, entity_id) VALUES (?, ?, ?)`,
+		userID, entityType, entityID)
+	return err
+}
+
+// Unmute removes a mute; it returns sql.ErrNoRows if there was none
+func (repo *NotificationSettingsRepository) Unmute(userID int, entityType string, entityID int) error {
+	result, err := repo.DB.Exec(`
+        DELETE FROM notification_mutes WHERE user_id = ? AND entity_type = ? AND entity_id = ?`,
+		userID, entityType, entityID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// notificationGroupQueries find the group behind each kind of entity a notification can point at
+var notificationGroupQueries = map[string]string{
+	models.NotificationEntityEvent:     `SELECT group_id FROM group_events WHERE id = ?`,
+	models.NotificationEntityGroupPost: `SELECT group_id FROM group_posts WHERE id = ?`,
+	models.NotificationEntityComment: `
+        SELECT gp.group_id FROM comments c JOIN group_posts gp ON c.group_post_id = gp.id WHERE c.id = ?`,
+}
+
+// IsMuted reports whether the recipient muted what a notification is about: its event or group
+// chat, or the group that content belongs to
+func (repo *NotificationSettingsRepository) IsMuted(notification *models.Notification) (bool, error) {
+	groupID := 0
+	switch notification.EntityType {
+	case models.NotificationEntityGroup, models.NotificationEntityGroupChat:
+		groupID = notification.EntityID
+	default:
+		query, ok := notificationGroupQueries[notification.EntityType]
+		if ok {
+			err := repo.DB.QueryRow(query, notification.EntityID).Scan(&groupID)
+			if err != nil && err != sql.ErrNoRows {
+				return false, err
+			}
+		}
+	}
+
+	var muted bool
+	err := repo.DB.QueryRow(`
+        SELECT EXISTS (SELECT 1 FROM notification_mutes WHERE user_id = ?
+            AND ((entity_type = ? AND entity_id = ?) OR (entity_type = 'group' AND entity_id = ?)))`,
+		notification.UserID, notification.EntityType, notification.EntityID, groupID).Scan(&muted)
+	return muted, err
+}
+
+// GetEmailDigestRecipients lists users with an email address who want at least one notification
+// type in their email digest and haven't been sent one within the last period
+func (repo *NotificationSettingsRepository) GetEmailDigestRecipients(period time.Duration) ([]models.EmailDigestRecipient, error) {
+	rows, err := repo.DB.Query(`
+        SELECT u.id, u.email, u.nickname FROM users u
+        LEFT JOIN notification_settings s ON s.user_id = u.id
+        WHERE IFNULL(u.email, '') != ''
+          AND EXISTS (SELECT 1 FROM notification_preferences p WHERE p.user_id = u.id AND p.email_digest = 1)
+          AND (s.email_digest_sent_at IS NULL OR datetime(s.email_digest_sent_at) <= datetime('now', ?))`,
+		"-"+strconv.Itoa(int(period.Seconds()))+" seconds")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []models.EmailDigestRecipient
+	for rows.Next() {
+		var recipient models.EmailDigestRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Email, &recipient.Nickname); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// GetEmailDigestWindow returns the period a user's next email digest covers: since their last
+// digest (or the past day for their first) until now
+func (repo *NotificationSettingsRepository) GetEmailDigestWindow(userID int) (since, until string, err error) {
+	err = repo.DB.QueryRow(`
+        SELECT COALESCE((SELECT datetime(email_digest_sent_at) FROM notification_settings WHERE user_id = ?),
+                        datetime('now', '-1 day')),
+               datetime('now')`, userID).Scan(&since, &until)
+	return since, until, err
+}
+
+// MarkEmailDigestSent records that the user's digest covered everything up to until
+func (repo *NotificationSettingsRepository) MarkEmailDigestSent(userID int, until string) error {
+	_, err := repo.DB.Exec(`
+        INSERT INTO notification_settings (user_id, email_digest_sent_at) VALUES (?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET email_digest_sent_at = excluded.email_digest_sent_at`,
+		userID, until)
+	return err
+}
+
+// GetOrCreateUnsubscribeToken returns the token for the user's email unsubscribe link
+func (repo *NotificationSettingsRepository) GetOrCreateUnsubscribeToken(userID int) (string, error) {
+	var token string
+	err := repo.DB.QueryRow(`SELECT token FROM email_unsubscribe_tokens WHERE user_id = ?`, userID).Scan(&token)
+	if err != sql.ErrNoRows {
+		return token, err
+	}
+	token, err = generateToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = repo.DB.Exec(`INSERT INTO email_unsubscribe_tokens (user_id, token) VALUES (?, ?)`, userID, token)
+	return token, err
+}
+
+// GetUnsubscribeTokenUser returns the owner of an unsubscribe token, or sql.ErrNoRows for unknown tokens
+func (repo *NotificationSettingsRepository) GetUnsubscribeTokenUser(token string) (int, error) {
+	var userID int
+	err := repo.DB.QueryRow(`SELECT user_id FROM email_unsubscribe_tokens WHERE token = ?`, token).Scan(&userID)
+	return userID, err
+}
+
+// UnsubscribeEmailDigest turns the email digest off for every notification type of the token's
+// owner; it returns sql.ErrNoRows for unknown tokens
+func (repo *NotificationSettingsRepository) UnsubscribeEmailDigest(token string) (int, error) {
+	userID, err := repo.GetUnsubscribeTokenUser(token)
+	if err != nil {
+		return 0, err
+	}
+	_, err = repo.DB.Exec(`UPDATE notification_preferences SET email_digest = 0 WHERE user_id = ?`, userID)
+	return userID, err
+}
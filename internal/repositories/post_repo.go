@@ -58,6 +58,37 @@ func (repo *PostRepository) GetUserPosts(userID int, viewerID int) ([]models.Pos
 	return posts, nil
 }
 
+// GetPostOwner returns the ID of the user who wrote a post
+func (repo *PostRepository) GetPostOwner(postID int) (int, error) {
+	var userID int
+	err := repo.DB.QueryRow(`SELECT user_id FROM posts WHERE id = ?`, postID).Scan(&userID)
+	return userID, err
+}
+
+// CanViewPost reports whether a user may see a post: their own, public ones, and followers-only
+// ones of users they follow. It returns sql.ErrNoRows if the post doesn't exist.
+func (repo *PostRepository) CanViewPost(userID, postID int) (bool, error) {
+	var ownerID int
+	var privacy sql.NullString
+	err := repo.DB.QueryRow(`SELECT user_id, privacy FROM posts WHERE id = ?`, postID).Scan(&ownerID, &privacy)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case ownerID == userID, !privacy.Valid, privacy.String == "public":
+		return true, nil
+	case privacy.String == "followers" || privacy.String == "followers-only":
+		var follows bool
+		err := repo.DB.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM followers WHERE follower_id = ? AND following_id = ? AND status = 'accepted')`,
+			userID, ownerID).Scan(&follows)
+		return follows, err
+	default:
+		return false, nil
+	}
+}
+
 // DeletePost deletes a post (only the creator can delete)
 func (repo *PostRepository) DeletePost(postID, userID int) error {
 	_, err := repo.DB.Exec(`DELETE FROM posts WHERE id = ? AND user_id = ?`, postID, userID)
@@ -29,9 +29,9 @@ func (repo *UserRepository) CreateUser(user *models.User) error {
 	}
 
 	_, err := repo.DB.Exec(`
-		INSERT INTO users (nickname, email, password, first_name, last_name) 
-		VALUES (?, ?, ?, ?, ?)`,
-		user.Nickname, user.Email, user.Password, user.FirstName, user.LastName)
+		INSERT INTO users (nickname, email, password, first_name, last_name, avatar) 
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		user.Nickname, user.Email, user.Password, user.FirstName, user.LastName, user.Avatar)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
@@ -82,3 +82,10 @@ func (repo *UserRepository) GetUserByEmailOrNickname(identifier string) (*models
 
 	return &user, storedPassword, nil
 }
+
+// GetNickname returns a user's nickname
+func (repo *UserRepository) GetNickname(userID int) (string, error) {
+	var nickname string
+	err := repo.DB.QueryRow(`SELECT nickname FROM users WHERE id = ?`, userID).Scan(&nickname)
+	return nickname, err
+}
@@ -0,0 +1,352 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"social-network/internal/models"
+)
+
+// WebhookRepository handles webhooks and their delivery queue
+type WebhookRepository struct {
+	DB *sql.DB
+}
+
+// NewWebhookRepository creates a new instance of WebhookRepository
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// scanWebhook reads a webhook selected with webhookColumns
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	if err := row.Scan(&webhook.ID, &webhook.GroupID, &webhook.CreatedBy, &webhook.URL, &webhook.Secret,
+		&events, &webhook.Active, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	webhook.Events = strings.Split(events, ",")
+	return &webhook, nil
+}
+
+// webhookColumns lists the columns scanned by scanWebhook
+const webhookColumns = `id, group_id, created_by, url, secret, events, active, created_at`
+
+// CreateWebhook stores a new webhook and fills in its ID and a freshly generated secret
+func (repo *WebhookRepository) CreateWebhook(webhook *models.Webhook) error {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return err
+	}
+
+	result, err := repo.DB.Exec(`
+        INSERT INTO webhooks (group_id, created_by, url, secret, events, active)
+        VALUES (?, ?, ?, ?, ?, 1)`,
+		webhook.GroupID, webhook.CreatedBy, webhook.URL, secret, strings.Join(webhook.Events, ","))
+	if err != nil {
+		log.Println("❌ Failed to create webhook:", err)
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	webhook.ID = int(id)
+	webhook.Secret = secret
+	webhook.Active = true
+	webhook.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// GetWebhook returns one of a group's webhooks, including its secret
+func (repo *WebhookRepository) GetWebhook(groupID, webhookID int) (*models.Webhook, error) {
+	return scanWebhook(repo.DB.QueryRow(`
+        SELECT `+webhookColumns+` FROM webhooks WHERE id = ? AND group_id = ?`, webhookID, groupID))
+}
+
+// GetGroupWebhooks lists a group's webhooks, oldest first. Secrets are left out.
+func (repo *WebhookRepository) GetGroupWebhooks(groupID int) ([]models.Webhook, error) {
+	rows, err := repo.DB.Query(`
+        SELECT `+webhookColumns+` FROM webhooks WHERE group_id = ? ORDER BY id`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = ""
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook changes a webhook's URL, events and whether it's active
+func (repo *WebhookRepository) UpdateWebhook(webhook *models.Webhook) error {
+	result, err := repo.DB.Exec(`
+        UPDATE webhooks SET url = ?, events = ?, active = ? WHERE id = ? AND group_id = ?`,
+		webhook.URL, strings.Join(webhook.Events, ","), webhook.Active, webhook.ID, webhook.GroupID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its deliveries
+func (repo *WebhookRepository) DeleteWebhook(groupID, webhookID int) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND group_id = ?`, webhookID, groupID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`
+        DELETE FROM webhook_delivery_attempts
+        WHERE delivery_id IN (SELECT id FROM webhook_deliveries WHERE webhook_id = ?)`, webhookID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, webhookID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EnqueueEvent queues a delivery of the event for every active webhook of the group subscribed
+// to it and returns how many were queued
+func (repo *WebhookRepository) EnqueueEvent(groupID int, event string, data interface{}) (int, error) {
+	payload, err := json.Marshal(models.WebhookPayload{
+		Event:     event,
+		GroupID:   groupID,
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := repo.DB.Query(`
+        SELECT `+webhookColumns+` FROM webhooks WHERE group_id = ? AND active = 1`, groupID)
+	if err != nil {
+		return 0, err
+	}
+	var webhookIDs []int
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if webhook.Subscribes(event) {
+			webhookIDs = append(webhookIDs, webhook.ID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, webhookID := range webhookIDs {
+		if _, err := repo.DB.Exec(`
+            INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at)
+            VALUES (?, ?, ?, ?)`, webhookID, event, string(payload), time.Now().UTC()); err != nil {
+			return 0, err
+		}
+	}
+	return len(webhookIDs), nil
+}
+
+// deliveryColumns lists the columns scanned by scanDelivery; queries alias webhook_deliveries as d
+const deliveryColumns = `d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+        IFNULL(d.redelivery_of, 0), d.created_at`
+
+// scanDelivery reads a delivery selected with deliveryColumns
+func scanDelivery(row interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var nextAttemptAt sql.NullTime
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &nextAttemptAt, &delivery.RedeliveryOf, &delivery.CreatedAt); err != nil {
+		return nil, err
+	}
+	if nextAttemptAt.Valid && delivery.Status == models.WebhookDeliveryPending {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	return &delivery, nil
+}
+
+// queryDeliveries runs a delivery query and scans every row
+func (repo *WebhookRepository) queryDeliveries(query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due, oldest first, taking at
+// most perWebhook from each webhook so one busy or failing endpoint can't fill the batch.
+// Deliveries of disabled webhooks wait until the webhook is enabled again.
+func (repo *WebhookRepository) GetDueDeliveries(now time.Time, perWebhook, limit int) ([]models.WebhookDelivery, error) {
+	return repo.queryDeliveries(`
+        SELECT `+deliveryColumns+` FROM (
+            SELECT d.*, ROW_NUMBER() OVER (PARTITION BY d.webhook_id ORDER BY d.next_attempt_at, d.id) AS rn
+            FROM webhook_deliveries d
+            JOIN webhooks w ON d.webhook_id = w.id
+            WHERE d.status = 'pending' AND w.active = 1 AND datetime(d.next_attempt_at) <= datetime(?)
+        ) d
+        WHERE d.rn <= ?
+        ORDER BY d.next_attempt_at, d.id LIMIT ?`, now, perWebhook, limit)
+}
+
+// GetWebhookByID returns a webhook with its secret, for signing deliveries
+func (repo *WebhookRepository) GetWebhookByID(webhookID int) (*models.Webhook, error) {
+	return scanWebhook(repo.DB.QueryRow(`
+        SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, webhookID))
+}
+
+// GetWebhookDeliveries pages through a webhook's deliveries, newest first
+func (repo *WebhookRepository) GetWebhookDeliveries(webhookID, beforeID, limit int) ([]models.WebhookDelivery, error) {
+	if beforeID == 0 {
+		return repo.queryDeliveries(`
+            SELECT `+deliveryColumns+` FROM webhook_deliveries d
+            WHERE d.webhook_id = ? ORDER BY d.id DESC LIMIT ?`, webhookID, limit)
+	}
+	return repo.queryDeliveries(`
+        SELECT `+deliveryColumns+` FROM webhook_deliveries d
+        WHERE d.webhook_id = ? AND d.id < ? ORDER BY d.id DESC LIMIT ?`, webhookID, beforeID, limit)
+}
+
+// GetDelivery returns one of a webhook's deliveries with its attempt log
+func (repo *WebhookRepository) GetDelivery(webhookID, deliveryID int) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(repo.DB.QueryRow(`
+        SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.id = ? AND d.webhook_id = ?`, deliveryID, webhookID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.DB.Query(`
+        SELECT id, delivery_id, response_status, error, duration_ms, attempted_at
+        FROM webhook_delivery_attempts WHERE delivery_id = ? ORDER BY id`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		if err := rows.Scan(&attempt.ID, &attempt.DeliveryID, &attempt.ResponseStatus, &attempt.Error,
+			&attempt.DurationMS, &attempt.AttemptedAt); err != nil {
+			return nil, err
+		}
+		delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+	}
+	return delivery, rows.Err()
+}
+
+// RecordAttempt logs an attempt at a delivery and moves it on: delivered on success, otherwise
+// rescheduled with exponential backoff, or failed once it has used up its attempts
+func (repo *WebhookRepository) RecordAttempt(delivery *models.WebhookDelivery, attempt models.WebhookAttempt, succeeded bool) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+        INSERT INTO webhook_delivery_attempts (delivery_id, response_status, error, duration_ms, attempted_at)
+        VALUES (?, ?, ?, ?, ?)`,
+		delivery.ID, attempt.ResponseStatus, attempt.Error, attempt.DurationMS, attempt.AttemptedAt); err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	delivery.NextAttemptAt = nil
+	switch {
+	case succeeded:
+		delivery.Status = models.WebhookDeliveryDelivered
+	case delivery.Attempts >= models.WebhookMaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+	default:
+		next := attempt.AttemptedAt.Add(models.WebhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if _, err := tx.Exec(`
+        UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ? WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Redeliver queues a fresh copy of a delivery, with the original payload and a new set of
+// attempts, and returns it
+func (repo *WebhookRepository) Redeliver(webhookID, deliveryID int) (*models.WebhookDelivery, error) {
+	original, err := scanDelivery(repo.DB.QueryRow(`
+        SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.id = ? AND d.webhook_id = ?`, deliveryID, webhookID))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result, err := repo.DB.Exec(`
+        INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, redelivery_of, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)`, webhookID, original.Event, original.Payload, now, original.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookDelivery{
+		ID:            int(id),
+		WebhookID:     webhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  original.ID,
+		CreatedAt:     now,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrInvalidURL is returned for webhook URLs that aren't absolute http or https URLs
+	ErrInvalidURL = errors.New("URL must be an absolute http or https URL")
+	// ErrNonPublicTarget is returned for webhook URLs that point at loopback, private, link-local
+	// or otherwise internal addresses, so webhooks can't be used to reach the server's network
+	ErrNonPublicTarget = errors.New("URL must point at a public address")
+)
+
+// nonPublicNetworks are the special-purpose ranges not covered by the net.IP predicates used in
+// IsPublicIP: "this network", carrier-grade NAT, IETF protocol assignments, documentation and
+// benchmarking ranges, reserved space and NAT64
+var nonPublicNetworks = parseNetworks(
+	"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15",
+	"198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4", "64:ff9b::/96", "2001:db8::/32",
+)
+
+// parseNetworks parses CIDR ranges that are known to be valid
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// IsPublicIP reports whether an address is publicly routable, i.e. not loopback, private,
+// link-local (including cloud metadata endpoints such as 169.254.169.254), multicast or reserved
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4 // Check IPv4-mapped IPv6 addresses as IPv4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveTimeout bounds the DNS lookup made when a webhook URL is validated
+const resolveTimeout = 5 * time.Second
+
+// ValidateURL checks that a webhook URL is an absolute http or https URL whose host only
+// resolves to public addresses. The sender checks the address again when it connects, as DNS
+// answers can change after validation.
+func ValidateURL(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrNonPublicTarget
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrNonPublicTarget
+		}
+	}
+	return nil
+}
+
+// newClient builds the HTTP client deliveries are sent with. Every connection is checked with
+// allowed right before it's made, which also covers hosts whose DNS answer changed since the URL
+// was validated, and redirects are returned as responses rather than followed.
+func newClient(allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return ErrNonPublicTarget
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:                 nil, // A proxy would be dialed instead of the target, bypassing Control
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost:   2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"social-network/internal/models"
+)
+
+// Headers sent with every delivery. Receivers verify a delivery by computing
+// HMAC-SHA256(secret, timestamp + "." + body) and comparing it with the signature header.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" followed by the hex digest
+)
+
+// Sign returns the signature header value for a body sent at the given Unix timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether a signature header value matches the body and timestamp
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// maxErrorBody caps how much of a failed response is kept in the attempt log
+const maxErrorBody = 512
+
+// Sender POSTs deliveries to webhook endpoints
+type Sender struct {
+	Client *http.Client
+}
+
+// NewSender creates a sender that only connects to public addresses, doesn't follow redirects
+// and gives up on a request after ten seconds
+func NewSender() *Sender {
+	return &Sender{Client: newClient(IsPublicIP)}
+}
+
+// Send makes one attempt at a delivery. The returned attempt describes what happened and the
+// bool reports whether the receiver accepted it with a 2xx response.
+func (s *Sender) Send(webhook *models.Webhook, delivery *models.WebhookDelivery) (models.WebhookAttempt, bool) {
+	started := time.Now().UTC()
+	attempt := models.WebhookAttempt{DeliveryID: delivery.ID, AttemptedAt: started}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	timestamp := started.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "social-network-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	attempt.DurationMS = int(time.Since(started) / time.Millisecond)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	defer resp.Body.Close()
+
+	attempt.ResponseStatus = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return attempt, true
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	attempt.Error = fmt.Sprintf("%s: %s", resp.Status, bytes.TrimSpace(snippet))
+	return attempt, false
+}
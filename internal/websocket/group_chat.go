@@ -1,10 +1,13 @@
 package websocket
 
 import (
+	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"sync"
 	"social-network/internal/config"
+	"social-network/internal/models"
 	"social-network/internal/repositories"
 
 	"github.com/gorilla/websocket"
@@ -16,6 +19,9 @@ type GroupChatManager struct {
 	Mutex        sync.Mutex
 }
 
+// Global group chat manager instance shared by the socket route and the REST handlers.
+var GroupChatHub = NewGroupChatManager()
+
 // ✅ NewGroupChatManager initializes a new group chat manager
 func NewGroupChatManager() *GroupChatManager {
 	return &GroupChatManager{
@@ -68,6 +74,13 @@ func (gm *GroupChatManager) ListenForMessages(conn *websocket.Conn, groupID, use
 			continue
 		}
 
+		// ✅ Archived groups are read-only
+		archived, err := repositories.NewGroupRepository(config.GetDB()).IsGroupArchived(groupID)
+		if err != nil || archived {
+			gm.sendToUser(groupID, userID, map[string]string{"type": "error", "content": "This group is archived and read-only"})
+			continue
+		}
+
 		log.Printf("📩 Group %d | User %d: %s", groupID, userID, message.Content)
 
 		// ✅ Broadcast the message to all users in the group
@@ -77,6 +90,9 @@ func (gm *GroupChatManager) ListenForMessages(conn *websocket.Conn, groupID, use
 
 // BroadcastGroupMessage sends a message to all users in a group chat and saves it
 func (gm *GroupChatManager) BroadcastGroupMessage(groupID, senderID int, content string) {
+	// Deferred first so mentions are notified after the lock is released
+	defer notifyChatMentions(groupID, senderID, content)
+
 	gm.Mutex.Lock()
 	defer gm.Mutex.Unlock()
 
@@ -106,6 +122,52 @@ func (gm *GroupChatManager) BroadcastGroupMessage(groupID, senderID int, content
 }
 
 
+// notifyChatMentions sends a mention notification to each group member @mentioned in a chat message
+func notifyChatMentions(groupID, senderID int, content string) {
+	nicknames := models.MentionedNicknames(content)
+	if len(nicknames) == 0 {
+		return
+	}
+
+	db := config.GetDB()
+	groupRepo := repositories.NewGroupRepository(db)
+	group, err := groupRepo.GetGroupByID(groupID)
+	if err != nil {
+		log.Printf("❌ Failed to load Group %d for mention notifications: %v", groupID, err)
+		return
+	}
+	members, err := groupRepo.GetGroupMembers(groupID)
+	if err != nil {
+		log.Printf("❌ Failed to load Group %d members for mention notifications: %v", groupID, err)
+		return
+	}
+
+	mentioned := make(map[string]bool)
+	for _, nickname := range nicknames {
+		mentioned[nickname] = true
+	}
+	var sender string
+	for _, member := range members {
+		if member.UserID == senderID {
+			sender = member.Nickname
+		}
+	}
+
+	for _, member := range members {
+		if member.UserID == senderID || !mentioned[strings.ToLower(member.Nickname)] {
+			continue
+		}
+		Notify(models.Notification{
+			UserID:     member.UserID,
+			Type:       models.NotificationTypeMention,
+			Message:    fmt.Sprintf("%s mentioned you in %s chat: %s", sender, group.Name, content),
+			ActorID:    senderID,
+			EntityType: models.NotificationEntityGroupChat,
+			EntityID:   groupID,
+		})
+	}
+}
+
 // ✅ Remove User From Group When They Disconnect
 func (gm *GroupChatManager) RemoveUserFromGroup(groupID, userID int) {
 	gm.Mutex.Lock()
@@ -117,3 +179,41 @@ func (gm *GroupChatManager) RemoveUserFromGroup(groupID, userID int) {
 		log.Printf("⚠️ User %d removed from Group %d chat.", userID, groupID)
 	}
 }
+
+// sendToUser writes a payload to a single user's socket in a group chat
+func (gm *GroupChatManager) sendToUser(groupID, userID int, payload interface{}) {
+	gm.Mutex.Lock()
+	conn, exists := gm.GroupClients[groupID][userID]
+	gm.Mutex.Unlock()
+	if !exists {
+		return
+	}
+
+	conn.Mutex.Lock()
+	err := conn.Conn.WriteJSON(payload)
+	conn.Mutex.Unlock()
+	if err != nil {
+		log.Printf("❌ Error sending to User %d in Group %d: %v", userID, groupID, err)
+	}
+}
+
+// DisconnectGroup tells every connected user that the group is gone and closes their sockets
+func (gm *GroupChatManager) DisconnectGroup(groupID int) {
+	gm.Mutex.Lock()
+	defer gm.Mutex.Unlock()
+
+	for userID, conn := range gm.GroupClients[groupID] {
+		conn.Mutex.Lock()
+		conn.Conn.WriteJSON(map[string]string{"type": "group_deleted", "content": "This group has been deleted"})
+		conn.Mutex.Unlock()
+		conn.Conn.Close()
+		log.Printf("⚠️ User %d disconnected from deleted Group %d chat.", userID, groupID)
+	}
+	delete(gm.GroupClients, groupID)
+}
+
+// EvictUser notifies a user that they were removed from a group and closes their chat socket
+func (gm *GroupChatManager) EvictUser(groupID, userID int, reason string) {
+	gm.sendToUser(groupID, userID, map[string]string{"type": "removed", "content": reason})
+	gm.RemoveUserFromGroup(groupID, userID)
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+
+	"social-network/internal/models"
+)
+
+// notificationStreamBuffer is how many events a slow Server-Sent Events client may fall behind
+// before its stream is dropped
+const notificationStreamBuffer = 32
+
+// NotificationStream receives a user's live notifications for a Server-Sent Events connection.
+// Events is closed when the stream is dropped for falling behind.
+type NotificationStream struct {
+	UserID int
+	Events chan StreamEvent
+}
+
+// StreamEvent is one Server-Sent Event
+type StreamEvent struct {
+	ID    int    // Notification ID to resume from, 0 for events like unread counts
+	Event string // "notification", "notification_removed" or "unread_count"
+	Data  []byte // JSON, in the same shape as the WebSocket messages
+}
+
+// NewStreamEvent wraps a message pushed to the notification socket as a Server-Sent Event
+func NewStreamEvent(message interface{}) (StreamEvent, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return StreamEvent{}, err
+	}
+	switch m := message.(type) {
+	case models.Notification:
+		return StreamEvent{ID: m.ID, Event: "notification", Data: data}, nil
+	case UnreadCountMessage:
+		return StreamEvent{Event: m.Type, Data: data}, nil
+	case NotificationRemovedMessage:
+		return StreamEvent{Event: m.Type, Data: data}, nil
+	default:
+		return StreamEvent{Event: "message", Data: data}, nil
+	}
+}
+
+// Subscribe opens a notification stream for the user. A user may hold several streams, one per
+// open tab, alongside their WebSocket.
+func (wm *WebSocketNotificationManager) Subscribe(userID int) *NotificationStream {
+	wm.Mutex.Lock()
+	defer wm.Mutex.Unlock()
+
+	if wm.Streams == nil {
+		wm.Streams = make(map[int]map[*NotificationStream]bool)
+	}
+	if wm.Streams[userID] == nil {
+		wm.Streams[userID] = make(map[*NotificationStream]bool)
+	}
+	stream := &NotificationStream{UserID: userID, Events: make(chan StreamEvent, notificationStreamBuffer)}
+	wm.Streams[userID][stream] = true
+	log.Printf("✅ User %d connected to the notification stream.", userID)
+	return stream
+}
+
+// Unsubscribe closes a notification stream
+func (wm *WebSocketNotificationManager) Unsubscribe(stream *NotificationStream) {
+	wm.Mutex.Lock()
+	defer wm.Mutex.Unlock()
+	wm.removeStream(stream)
+}
+
+// removeStream forgets a stream and closes its channel; the caller holds the mutex
+func (wm *WebSocketNotificationManager) removeStream(stream *NotificationStream) {
+	streams := wm.Streams[stream.UserID]
+	if !streams[stream] {
+		return
+	}
+	delete(streams, stream)
+	if len(streams) == 0 {
+		delete(wm.Streams, stream.UserID)
+	}
+	close(stream.Events)
+	log.Printf("⚠️ User %d disconnected from the notification stream.", stream.UserID)
+}
+
+// publish hands a message to every notification stream the user has open. Streams that have
+// fallen too far behind are dropped; the client reconnects and resumes with Last-Event-ID.
+func (wm *WebSocketNotificationManager) publish(userID int, message interface{}) bool {
+	wm.Mutex.Lock()
+	defer wm.Mutex.Unlock()
+
+	if len(wm.Streams[userID]) == 0 {
+		return false
+	}
+	event, err := NewStreamEvent(message)
+	if err != nil {
+		log.Printf("❌ Error encoding stream event for User %d: %v", userID, err)
+		return false
+	}
+
+	delivered := false
+	for stream := range wm.Streams[userID] {
+		select {
+		case stream.Events <- event:
+			delivered = true
+		default:
+			log.Printf("⚠️ Notification stream for User %d fell behind, dropping it.", userID)
+			wm.removeStream(stream)
+		}
+	}
+	return delivered
+}
@@ -3,32 +3,48 @@ package websocket
 import (
 	"log"
 	"sync"
+	"time"
 
 	"social-network/internal/config"
 	"social-network/internal/models"
+	"social-network/internal/repositories"
 
 	"github.com/gorilla/websocket"
 )
 
-// WebSocketConn wraps a WebSocket connection.
+// WebSocketConn wraps a WebSocket connection. Mutex serializes writes, as the connection
+// allows only one concurrent writer.
 type WebSocketConn struct {
 	Conn  *websocket.Conn
 	Mutex sync.Mutex
 }
 
-// WebSocketNotificationManager manages WebSocket notifications.
+// notificationWriteTimeout bounds every write to a notification socket
+const notificationWriteTimeout = 10 * time.Second
+
+// WriteJSON sends a message on the socket
+func (c *WebSocketConn) WriteJSON(message interface{}) error {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	c.Conn.SetWriteDeadline(time.Now().Add(notificationWriteTimeout))
+	return c.Conn.WriteJSON(message)
+}
+
+// Ping sends a ping frame so dead connections are noticed
+func (c *WebSocketConn) Ping() error {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(notificationWriteTimeout))
+}
+
+// WebSocketNotificationManager manages live notification connections: one WebSocket per user,
+// plus any Server-Sent Events streams they have open.
 type WebSocketNotificationManager struct {
 	Clients map[int]*WebSocketConn
+	Streams map[int]map[*NotificationStream]bool
 	Mutex   sync.Mutex
 }
 
-// Notification represents a real-time notification (for WebSocket output).
-type Notification struct {
-	Type    string `json:"type"`
-	UserID  int    `json:"user_id"`
-	Message string `json:"message"`
-}
-
 // Global Notification Manager instance.
 var NotificationManager = &WebSocketNotificationManager{
 	Clients: make(map[int]*WebSocketConn),
@@ -41,54 +57,180 @@ func NewWebSocketNotificationManager() *WebSocketNotificationManager {
 	}
 }
 
-// SendNotification sends a notification to a user via WebSocket.
-// If sending fails or the user is offline, it stores the notification in the database.
+// SendNotification stores a plain notification and sends it to the user via WebSocket if they're online.
 func SendNotification(userID int, notifType, message string) {
-	notification := models.Notification{
-		UserID:  userID,
-		Type:    notifType,
-		Message: message,
-		IsRead:  false,
+	Notify(models.Notification{UserID: userID, Type: notifType, Message: message})
+}
+
+// Notify delivers a structured notification, logging any failure. See Deliver.
+func Notify(notification models.Notification) {
+	if err := Deliver(&notification); err != nil {
+		log.Printf("❌ Failed to store notification for User %d: %v", notification.UserID, err)
+	}
+}
+
+// Deliver sends a notification through the channels the recipient chose for its type. It's dropped
+// if they turned every channel off or muted what it's about. Otherwise it's stored (hidden from the
+// app if only the email digest wants it) and, if push is on, sent over their live socket in the
+// same shape the REST API returns, followed by their new unread count. Likes and comments are
+// folded into a recent notification about the same item when there is one; the updated
+// notification is then re-sent with its original ID so clients can replace it.
+func Deliver(notification *models.Notification) error {
+	db := config.GetDB()
+	pref, muted := recipientPreference(notification)
+	if muted || !pref.Enabled() {
+		log.Printf("🔕 Skipping %s notification for User %d", notification.Type, notification.UserID)
+		return nil
+	}
+
+	if notification.ActorID != 0 && notification.ActorNickname == "" {
+		nickname, err := repositories.NewUserRepository(db).GetNickname(notification.ActorID)
+		if err == nil {
+			notification.ActorNickname = nickname
+		}
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now().UTC()
+	}
+	notification.Hidden = !pref.InApp
+
+	if !models.IsAggregatable(notification.Type) || !aggregateNotification(notification) {
+		repo := repositories.NewNotificationRepository(db)
+		if err := repo.SaveNotification(notification); err != nil {
+			return err
+		}
+		log.Printf("✅ Notification stored for User %d", notification.UserID)
+	}
+	if pref.Push && PushNotification(*notification) && pref.InApp {
+		PushUnreadCount(notification.UserID)
+	}
+	return nil
+}
+
+// recipientPreference loads the recipient's preference for the notification's type, falling back
+// to the default, and whether they muted what it's about
+func recipientPreference(notification *models.Notification) (models.NotificationPreference, bool) {
+	settingsRepo := repositories.NewNotificationSettingsRepository(config.GetDB())
+	pref, err := settingsRepo.GetPreference(notification.UserID, notification.Type)
+	if err != nil {
+		log.Printf("❌ Failed to load notification preferences for User %d: %v", notification.UserID, err)
+		pref = models.DefaultNotificationPreference(notification.Type)
+	}
+	muted, err := settingsRepo.IsMuted(notification)
+	if err != nil {
+		log.Printf("❌ Failed to check notification mutes for User %d: %v", notification.UserID, err)
+	}
+	return pref, muted
+}
+
+// PushChanges tells recipients' live sockets about notifications changed after delivery, such as
+// aggregates an actor was withdrawn from: updated ones are re-sent with their ID and removed ones
+// announced by ID. As in Deliver, nothing is pushed to a recipient who muted the item or turned
+// push off for its type, and unread counts only follow notifications shown in the app.
+func PushChanges(updated, removed []models.Notification) {
+	for _, notification := range updated {
+		if pref, muted := recipientPreference(&notification); muted || !pref.Push {
+			continue
+		}
+		if PushNotification(notification) && !notification.Hidden {
+			PushUnreadCount(notification.UserID)
+		}
+	}
+	for _, notification := range removed {
+		if pref, muted := recipientPreference(&notification); muted || !pref.Push {
+			continue
+		}
+		if pushNotificationRemoved(notification) && !notification.Hidden {
+			PushUnreadCount(notification.UserID)
+		}
+	}
+}
+
+// PushNotification delivers an already stored notification to the user's live socket.
+// It reports whether the user was online and the write succeeded.
+func PushNotification(notification models.Notification) bool {
+	if !pushToUser(notification.UserID, notification) {
+		return false
 	}
+	log.Printf("✅ WebSocket notification sent to User %d", notification.UserID)
+	return true
+}
+
+// NotificationRemovedMessage tells a connected client that a notification it was sent has been
+// deleted, e.g. when the only like it was about is taken back, so the client can drop it
+type NotificationRemovedMessage struct {
+	Type           string `json:"type"` // Always "notification_removed"
+	NotificationID int    `json:"notification_id"`
+}
+
+// pushNotificationRemoved tells the user's live socket that a notification was deleted
+func pushNotificationRemoved(notification models.Notification) bool {
+	return pushToUser(notification.UserID, NotificationRemovedMessage{Type: "notification_removed", NotificationID: notification.ID})
+}
+
+// UnreadCountMessage tells a connected client how many unread notifications its user has
+type UnreadCountMessage struct {
+	Type        string `json:"type"` // Always "unread_count"
+	UnreadCount int    `json:"unread_count"`
+}
+
+// PushUnreadCount sends the user's current unread count to their live socket, if they have one
+func PushUnreadCount(userID int) {
+	if !NotificationManager.IsOnline(userID) {
+		return
+	}
+	count, err := repositories.NewNotificationRepository(config.GetDB()).CountUnread(userID)
+	if err != nil {
+		log.Printf("❌ Failed to count unread notifications for User %d: %v", userID, err)
+		return
+	}
+	pushToUser(userID, UnreadCountMessage{Type: "unread_count", UnreadCount: count})
+}
+
+// pushToUser sends a message to the user's notification streams and socket, dropping the socket
+// on failure. It reports whether any of them received it.
+func pushToUser(userID int, message interface{}) bool {
+	streamed := NotificationManager.publish(userID, message)
 
 	NotificationManager.Mutex.Lock()
 	client, exists := NotificationManager.Clients[userID]
 	NotificationManager.Mutex.Unlock()
 
-	if exists && client != nil {
-		client.Mutex.Lock()
-		err := client.Conn.WriteJSON(notification)
-		client.Mutex.Unlock()
-
-		if err != nil {
-			log.Printf("❌ Error sending WebSocket notification to User %d: %v", userID, err)
-			NotificationManager.RemoveClient(userID)
-			storeNotification(notification) // Fallback: store in DB
-		} else {
-			log.Printf("✅ WebSocket notification sent to User %d", userID)
+	if !exists || client == nil {
+		if !streamed {
+			log.Printf("📌 User %d is offline.", userID)
 		}
-	} else {
-		log.Printf("📌 User %d is offline. Storing notification.", userID)
-		// storeNotification(notification)
+		return streamed
+	}
+
+	if err := client.WriteJSON(message); err != nil {
+		log.Printf("❌ Error sending WebSocket notification to User %d: %v", userID, err)
+		NotificationManager.RemoveClient(userID, client)
+		return streamed
 	}
+	return true
 }
 
-// storeNotification stores the notification in the database (fallback when WebSocket sending fails).
-func storeNotification(notification models.Notification) {
-	db := config.GetDB()
-	_, err := db.Exec(`
-		INSERT INTO notifications (user_id, type, message, is_read, created_at) 
-		VALUES (?, ?, ?, 0, CURRENT_TIMESTAMP)`,
-		notification.UserID, notification.Type, notification.Message)
+// IsOnline reports whether the user has a live notification socket or stream
+func (wm *WebSocketNotificationManager) IsOnline(userID int) bool {
+	wm.Mutex.Lock()
+	defer wm.Mutex.Unlock()
+	return wm.Clients[userID] != nil || len(wm.Streams[userID]) > 0
+}
+
+// aggregateNotification folds the notification into an existing one, reporting whether it did
+func aggregateNotification(notification *models.Notification) bool {
+	repo := repositories.NewNotificationRepository(config.GetDB())
+	aggregated, err := repo.AggregateNotification(notification, models.NotificationAggregationWindow)
 	if err != nil {
-		log.Printf("❌ Failed to store notification for User %d: %v", notification.UserID, err)
-	} else {
-		log.Printf("✅ Notification stored for User %d", notification.UserID)
+		log.Printf("❌ Failed to aggregate notification for User %d: %v", notification.UserID, err)
+		return false
 	}
+	return aggregated
 }
 
-// RegisterClient registers a WebSocket client for notifications.
-func (wm *WebSocketNotificationManager) RegisterClient(userID int, conn *websocket.Conn) {
+// RegisterClient registers a WebSocket client for notifications, replacing any previous one.
+func (wm *WebSocketNotificationManager) RegisterClient(userID int, conn *websocket.Conn) *WebSocketConn {
 	wm.Mutex.Lock()
 	defer wm.Mutex.Unlock()
 
@@ -99,17 +241,20 @@ func (wm *WebSocketNotificationManager) RegisterClient(userID int, conn *websock
 		delete(wm.Clients, userID)
 	}
 
-	wm.Clients[userID] = &WebSocketConn{Conn: conn}
+	client := &WebSocketConn{Conn: conn}
+	wm.Clients[userID] = client
 	log.Printf("✅ User %d connected for real-time notifications.", userID)
+	return client
 }
 
-// RemoveClient removes a disconnected WebSocket client.
-func (wm *WebSocketNotificationManager) RemoveClient(userID int) {
+// RemoveClient closes a disconnected WebSocket client. It's only unregistered if it's still the
+// user's current client, so a replaced connection shutting down doesn't drop its successor.
+func (wm *WebSocketNotificationManager) RemoveClient(userID int, client *WebSocketConn) {
 	wm.Mutex.Lock()
 	defer wm.Mutex.Unlock()
 
-	if client, exists := wm.Clients[userID]; exists {
-		client.Conn.Close()
+	client.Conn.Close()
+	if wm.Clients[userID] == client {
 		delete(wm.Clients, userID)
 		log.Printf("⚠️ User %d disconnected from notifications.", userID)
 	}
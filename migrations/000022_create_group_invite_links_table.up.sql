@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS group_invite_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER NOT NULL,
+    token TEXT UNIQUE NOT NULL,
+    created_by INTEGER NOT NULL,
+    expires_at TIMESTAMP DEFAULT NULL, -- NULL means the link never expires
+    max_uses INTEGER DEFAULT NULL,     -- NULL means unlimited uses
+    uses INTEGER NOT NULL DEFAULT 0,
+    revoked BOOLEAN NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
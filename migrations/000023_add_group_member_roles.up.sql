@@ -0,0 +1,32 @@
+PRAGMA foreign_keys=off;
+
+-- Rename the old table
+ALTER TABLE group_members RENAME TO group_members_old;
+
+-- Recreate the table with the full role set (owner > admin > moderator > member)
+CREATE TABLE group_members (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    status TEXT CHECK(status IN ('pending', 'member', 'moderator', 'admin', 'owner')) DEFAULT 'pending',
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(group_id, user_id)
+);
+
+-- Copy data from the old table to the new one
+INSERT INTO group_members (id, group_id, user_id, status, joined_at)
+SELECT id, group_id, user_id, status, joined_at FROM group_members_old;
+
+-- Drop the old table
+DROP TABLE group_members_old;
+
+-- Every group creator becomes the owner of their group
+INSERT OR IGNORE INTO group_members (group_id, user_id, status, joined_at)
+SELECT id, creator_id, 'owner', created_at FROM groups;
+
+UPDATE group_members SET status = 'owner'
+WHERE EXISTS (SELECT 1 FROM groups g WHERE g.id = group_members.group_id AND g.creator_id = group_members.user_id);
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,15 @@
+-- Archived groups are read-only; NULL means the group is active
+ALTER TABLE groups ADD COLUMN archived_at TIMESTAMP DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS group_ownership_transfers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER NOT NULL,
+    from_user_id INTEGER NOT NULL,
+    to_user_id INTEGER NOT NULL,
+    status TEXT CHECK(status IN ('pending', 'accepted', 'declined', 'cancelled')) DEFAULT 'pending',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS group_bans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    banned_by INTEGER NOT NULL,
+    reason TEXT DEFAULT NULL,
+    expires_at TIMESTAMP DEFAULT NULL, -- NULL means the ban is permanent
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (banned_by) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(group_id, user_id)
+);
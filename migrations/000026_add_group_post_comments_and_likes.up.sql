@@ -0,0 +1,3 @@
+-- Comments and likes can target a group post instead of a regular post
+ALTER TABLE comments ADD COLUMN group_post_id INTEGER DEFAULT NULL REFERENCES group_posts(id) ON DELETE CASCADE;
+ALTER TABLE likes ADD COLUMN group_post_id INTEGER DEFAULT NULL REFERENCES group_posts(id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+-- Track edits and cancellations of group events; cancelled events stay visible
+ALTER TABLE group_events ADD COLUMN updated_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE group_events ADD COLUMN cancelled_at TIMESTAMP DEFAULT NULL;
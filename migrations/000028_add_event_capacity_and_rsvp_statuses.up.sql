@@ -0,0 +1,29 @@
+PRAGMA foreign_keys=off;
+
+-- Optional attendee limit; NULL means unlimited
+ALTER TABLE group_events ADD COLUMN capacity INTEGER DEFAULT NULL;
+
+-- Rename the old table
+ALTER TABLE event_rsvps RENAME TO event_rsvps_old;
+
+-- Recreate the table with the extra statuses and a timestamp for waitlist ordering
+CREATE TABLE event_rsvps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    status TEXT CHECK(status IN ('going', 'not going', 'interested', 'maybe', 'waitlisted')) NOT NULL DEFAULT 'not going',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(event_id, user_id)
+);
+
+-- Copy data from the old table to the new one
+INSERT INTO event_rsvps (id, event_id, user_id, status, created_at, updated_at)
+SELECT id, event_id, user_id, status, created_at, created_at FROM event_rsvps_old;
+
+-- Drop the old table
+DROP TABLE event_rsvps_old;
+
+PRAGMA foreign_keys=on;
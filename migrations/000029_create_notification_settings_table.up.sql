@@ -0,0 +1,7 @@
+-- Per-user notification settings; users without a row get the defaults
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id INTEGER PRIMARY KEY,
+    rsvp_digest BOOLEAN NOT NULL DEFAULT 0, -- Batch RSVPs to my events instead of one notification each
+    rsvp_digest_sent_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+-- Revision counter for calendar clients; bumped whenever an event changes or is cancelled
+ALTER TABLE group_events ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+
+-- Secret tokens for per-user calendar subscription feeds
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    user_id INTEGER PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
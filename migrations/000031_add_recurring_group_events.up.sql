@@ -0,0 +1,48 @@
+PRAGMA foreign_keys=off;
+
+-- Recurrence rule; a NULL frequency means a one-off event
+ALTER TABLE group_events ADD COLUMN recurrence_freq TEXT DEFAULT NULL CHECK(recurrence_freq IN ('daily', 'weekly', 'monthly'));
+ALTER TABLE group_events ADD COLUMN recurrence_interval INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE group_events ADD COLUMN recurrence_until TEXT DEFAULT NULL; -- RFC 3339, like event_date
+ALTER TABLE group_events ADD COLUMN recurrence_count INTEGER DEFAULT NULL;
+
+-- Per-occurrence changes to a recurring event, keyed by the occurrence's originally scheduled start.
+-- Excluded occurrences are skipped; NULL fields inherit from the series.
+CREATE TABLE IF NOT EXISTS group_event_occurrences (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL,
+    excluded BOOLEAN NOT NULL DEFAULT 0,
+    title TEXT DEFAULT NULL,
+    description TEXT DEFAULT NULL,
+    event_date TEXT DEFAULT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    UNIQUE(event_id, occurrence_date)
+);
+
+-- Rename the old table
+ALTER TABLE event_rsvps RENAME TO event_rsvps_old;
+
+-- Recreate the table so users can answer each occurrence separately ('' for one-off events)
+CREATE TABLE event_rsvps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL DEFAULT '',
+    status TEXT CHECK(status IN ('going', 'not going', 'interested', 'maybe', 'waitlisted')) NOT NULL DEFAULT 'not going',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(event_id, user_id, occurrence_date)
+);
+
+-- Copy data from the old table to the new one
+INSERT INTO event_rsvps (id, event_id, user_id, status, created_at, updated_at)
+SELECT id, event_id, user_id, status, created_at, updated_at FROM event_rsvps_old;
+
+-- Drop the old table
+DROP TABLE event_rsvps_old;
+
+PRAGMA foreign_keys=on;
@@ -0,0 +1,15 @@
+-- Minutes before an event at which to remind the user, comma-separated
+ALTER TABLE notification_settings ADD COLUMN reminder_offsets TEXT NOT NULL DEFAULT '1440,60';
+
+-- Reminders already sent, so a restart never sends one twice
+CREATE TABLE IF NOT EXISTS event_reminders_sent (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    occurrence_date TEXT NOT NULL DEFAULT '',
+    user_id INTEGER NOT NULL,
+    offset_minutes INTEGER NOT NULL,
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(event_id, occurrence_date, user_id, offset_minutes)
+);
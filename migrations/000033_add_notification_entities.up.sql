@@ -0,0 +1,5 @@
+-- Structured notifications: who did it, what it's about, and extra data for rendering
+ALTER TABLE notifications ADD COLUMN actor_id INTEGER DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE notifications ADD COLUMN entity_type TEXT DEFAULT NULL;
+ALTER TABLE notifications ADD COLUMN entity_id INTEGER DEFAULT NULL;
+ALTER TABLE notifications ADD COLUMN data TEXT DEFAULT NULL; -- JSON object
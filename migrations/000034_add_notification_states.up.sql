@@ -0,0 +1,2 @@
+-- Actionable notifications (follow and group join requests) track whether they were answered
+ALTER TABLE notifications ADD COLUMN state TEXT DEFAULT NULL; -- pending, accepted or declined
@@ -0,0 +1,23 @@
+-- Per-type channel choices; types without a row use the defaults (in-app and push on, email digest off)
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    in_app BOOLEAN NOT NULL DEFAULT 1,
+    push BOOLEAN NOT NULL DEFAULT 1,
+    email_digest BOOLEAN NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Groups, events and group chats a user doesn't want notifications about
+CREATE TABLE IF NOT EXISTS notification_mutes (
+    user_id INTEGER NOT NULL,
+    entity_type TEXT NOT NULL CHECK (entity_type IN ('group', 'event', 'group_chat')),
+    entity_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, entity_type, entity_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Notifications kept only for other channels (the email digest) are hidden from the in-app list
+ALTER TABLE notifications ADD COLUMN in_app BOOLEAN NOT NULL DEFAULT 1;
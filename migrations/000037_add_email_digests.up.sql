@@ -0,0 +1,10 @@
+-- When each user's last email digest went out, so notifications aren't mailed twice
+ALTER TABLE notification_settings ADD COLUMN email_digest_sent_at TIMESTAMP DEFAULT NULL;
+
+-- Secret tokens behind the unsubscribe links in digest emails
+CREATE TABLE IF NOT EXISTS email_unsubscribe_tokens (
+    user_id INTEGER PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Path of the user's profile picture under /uploads, NULL when they haven't set one
+ALTER TABLE users ADD COLUMN avatar TEXT DEFAULT NULL;
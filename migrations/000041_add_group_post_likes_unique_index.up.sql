@@ -0,0 +1,8 @@
+-- The likes UNIQUE constraint doesn't cover group posts (NULL columns never collide), so keep
+-- each user's earliest like of a group post and enforce one like per user from now on
+DELETE FROM likes WHERE group_post_id IS NOT NULL AND id NOT IN (
+    SELECT MIN(id) FROM likes WHERE group_post_id IS NOT NULL GROUP BY user_id, group_post_id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_user_group_post ON likes(user_id, group_post_id)
+WHERE group_post_id IS NOT NULL;
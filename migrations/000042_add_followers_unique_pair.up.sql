@@ -0,0 +1,12 @@
+-- Concurrent follow requests could store the same pair twice. Keep one row per pair, preferring
+-- an accepted follow over a pending request, and allow only one from now on
+DELETE FROM followers WHERE id NOT IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY follower_id, following_id ORDER BY status = 'accepted' DESC, id
+        ) AS pair_rank
+        FROM followers
+    ) WHERE pair_rank = 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_followers_pair ON followers(follower_id, following_id);